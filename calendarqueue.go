@@ -0,0 +1,227 @@
+package steps
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	// calendarMinBuckets is the smallest number of buckets a calendar queue shrinks to.
+	calendarMinBuckets = 2
+
+	// calendarSampleSize is the number of events sampled to estimate the bucket width when resizing.
+	calendarSampleSize = 25
+)
+
+// NewCalendarQueue creates a calendar queue[1] based Queue. A calendar queue spreads events across buckets ("days") of a fixed width that together make up a "year", and resizes itself as the number of events grows or shrinks. It has amortized O(1) push and pop when the time between events is reasonably uniform.
+//
+// [1]: https://doi.org/10.1145/63039.63045
+func NewCalendarQueue() Queue {
+	return &calendarQueue{
+		buckets: make([][]ScheduledEvent, calendarMinBuckets),
+		width:   int64(time.Second),
+		events:  newTombstones(),
+	}
+}
+
+// calendarQueue is a calendar queue. Removed events are lazily dropped when they reach the front of the queue.
+type calendarQueue struct {
+	// buckets holds the events. An event with key k is stored in bucket floorDiv(k, width) mod len(buckets). Each bucket is sorted using ScheduledEvent.Before.
+	buckets [][]ScheduledEvent
+	width   int64
+
+	// origin is the time of the first event pushed, used to compute event keys.
+	origin    time.Time
+	hasOrigin bool
+
+	// cursor is the (non-wrapped) bucket number to start looking for the next event in. No event stored in the queue is in a bucket before cursor.
+	cursor int64
+
+	// stored is the number of events in the buckets, including removed ones.
+	stored int
+	events tombstones
+}
+
+// Push adds an event to the queue.
+func (q *calendarQueue) Push(e ScheduledEvent) {
+	q.events.add(e)
+	if !q.hasOrigin {
+		q.origin = e.Event.When
+		q.hasOrigin = true
+	}
+	q.insert(e)
+	q.stored++
+	if q.stored > 2*len(q.buckets) {
+		q.resize(2 * len(q.buckets))
+	}
+}
+
+// insert puts the event in its bucket, keeping the bucket sorted.
+func (q *calendarQueue) insert(e ScheduledEvent) {
+	abs := floorDiv(eventKey(q.origin, e.Event.When), q.width)
+	if abs < q.cursor {
+		q.cursor = abs
+	}
+	i := q.bucketIndex(abs)
+	bucket := q.buckets[i]
+	index, _ := slices.BinarySearchFunc(bucket, e, compareEvents)
+	q.buckets[i] = slices.Insert(bucket, index, e)
+}
+
+// Pop removes the next event from the queue.
+func (q *calendarQueue) Pop() ScheduledEvent {
+	for {
+		e := q.popAny()
+		if q.events.forget(e) {
+			if len(q.buckets) > calendarMinBuckets && q.stored < len(q.buckets)/2 {
+				q.resize(len(q.buckets) / 2)
+			}
+			return e
+		}
+	}
+}
+
+// Peek returns the next event in the queue without removing it.
+func (q *calendarQueue) Peek() ScheduledEvent {
+	for {
+		i, _ := q.next()
+		e := q.buckets[i][0]
+		if q.events.isLive(e) {
+			return e
+		}
+		// Drop the removed event so that we don't have to skip it again.
+		q.popAny()
+		q.events.forget(e)
+	}
+}
+
+// Remove removes an event from the queue. Returns true if the event was found and removed, false otherwise.
+func (q *calendarQueue) Remove(id EventID) bool {
+	return q.events.remove(id)
+}
+
+// Len returns the number of events in the queue.
+func (q *calendarQueue) Len() int {
+	return q.events.len()
+}
+
+// Lookup returns the event with the given ID.
+func (q *calendarQueue) Lookup(id EventID) (ScheduledEvent, bool) {
+	return q.events.lookup(id)
+}
+
+// Update replaces the event that has the same ID as e with e. The old event is left in the queue, to be skipped when it is popped.
+func (q *calendarQueue) Update(e ScheduledEvent) bool {
+	if !q.events.remove(e.ID) {
+		return false
+	}
+	q.Push(e)
+	return true
+}
+
+// popAny removes the next event from the buckets, regardless of whether it has been removed or not.
+func (q *calendarQueue) popAny() ScheduledEvent {
+	i, abs := q.next()
+	e := q.buckets[i][0]
+	q.buckets[i] = slices.Delete(q.buckets[i], 0, 1)
+	q.cursor = abs
+	q.stored--
+	return e
+}
+
+// next returns the index of the bucket holding the next event together with its non-wrapped bucket number. It must only be called if the buckets are non-empty.
+func (q *calendarQueue) next() (int, int64) {
+	abs := q.cursor
+	for range q.buckets {
+		i := q.bucketIndex(abs)
+		if bucket := q.buckets[i]; len(bucket) > 0 && floorDiv(eventKey(q.origin, bucket[0].Event.When), q.width) <= abs {
+			return i, abs
+		}
+		abs++
+	}
+
+	// We went through a whole year without finding anything. Fall back to a direct search among the first event of every bucket.
+	best := -1
+	for i, bucket := range q.buckets {
+		if len(bucket) > 0 && (best < 0 || bucket[0].Before(q.buckets[best][0])) {
+			best = i
+		}
+	}
+	return best, floorDiv(eventKey(q.origin, q.buckets[best][0].Event.When), q.width)
+}
+
+func (q *calendarQueue) bucketIndex(abs int64) int {
+	n := int64(len(q.buckets))
+	return int(((abs % n) + n) % n)
+}
+
+// resize redistributes the events into nbuckets buckets, picking a new bucket width based on the separation between the first events in the queue.
+func (q *calendarQueue) resize(nbuckets int) {
+	all := make([]ScheduledEvent, 0, q.stored)
+	for _, bucket := range q.buckets {
+		for _, e := range bucket {
+			if q.events.isLive(e) {
+				all = append(all, e)
+			} else {
+				q.events.forget(e)
+			}
+		}
+	}
+	slices.SortFunc(all, compareEvents)
+
+	if width := q.estimateWidth(all); width > 0 {
+		q.width = width
+	}
+	q.buckets = make([][]ScheduledEvent, nbuckets)
+	q.stored = 0
+	for _, e := range all {
+		abs := floorDiv(eventKey(q.origin, e.Event.When), q.width)
+		i := q.bucketIndex(abs)
+		// Appending keeps the buckets sorted since all is sorted.
+		q.buckets[i] = append(q.buckets[i], e)
+		q.stored++
+	}
+	if len(all) > 0 {
+		q.cursor = floorDiv(eventKey(q.origin, all[0].Event.When), q.width)
+	}
+}
+
+// estimateWidth returns three times the average separation between the first sorted events, ignoring outliers. Returns zero if no estimate could be made.
+func (q *calendarQueue) estimateWidth(sorted []ScheduledEvent) int64 {
+	sample := sorted[:min(len(sorted), calendarSampleSize)]
+	if len(sample) < 2 {
+		return 0
+	}
+	separations := make([]int64, 0, len(sample)-1)
+	var total int64
+	for i := 1; i < len(sample); i++ {
+		d := eventKey(q.origin, sample[i].Event.When) - eventKey(q.origin, sample[i-1].Event.When)
+		separations = append(separations, d)
+		total += d
+	}
+	average := total / int64(len(separations))
+
+	var trimmedTotal, trimmedCount int64
+	for _, d := range separations {
+		if d <= 2*average {
+			trimmedTotal += d
+			trimmedCount++
+		}
+	}
+	if trimmedCount == 0 || trimmedTotal == 0 {
+		return 0
+	}
+	return 3 * trimmedTotal / trimmedCount
+}
+
+// compareEvents is a comparison function for slices.SortFunc and friends that orders events according to ScheduledEvent.Before.
+func compareEvents(a, b ScheduledEvent) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case b.Before(a):
+		return 1
+	default:
+		return 0
+	}
+}
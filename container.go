@@ -0,0 +1,216 @@
+package steps
+
+import (
+	"fmt"
+	"time"
+)
+
+// Container holds a continuous quantity, such as fuel in a tank, bytes in a buffer or money in a bank account, up to a capacity. Getting more than the current level waits until enough has been put, and putting more than there is room for waits until enough has been got.
+//
+// Waiting puts and gets are served like the acquisitions of a CountingSemaphore. By default, they are served in the order they started waiting, so a large get blocks the smaller ones behind it. See WithFairness.
+//
+// Puts and gets return a Request, which can be used to stop waiting. A put which didn't have to wait is served right away, while a get which didn't have to wait can be cancelled until its action runs, as long as there is room in the container to give its amount back.
+type Container struct {
+	sim      *Simulation
+	capacity float64
+	level    float64
+	fairness Fairness
+
+	// getters and putters hold the actions waiting to get and put amounts. gets and puts hold the amount each of them is waiting for.
+	getters *Condition
+	putters *Condition
+	gets    map[ConditionActionID]float64
+	puts    map[ConditionActionID]float64
+
+	// requested holds when each waiting get was made.
+	requested map[ConditionActionID]time.Time
+
+	// stats records statistics, if enabled using WithStats.
+	stats *statsRecorder
+}
+
+// NewContainer creates a new container holding initial, that can hold up to capacity. A capacity of zero or less means the container is unbounded, so putting never waits.
+func NewContainer(sim *Simulation, capacity, initial float64, opts ...ResourceOption) *Container {
+	if initial < 0 || (capacity > 0 && initial > capacity) {
+		panic(fmt.Sprintf("initial level %g must be between zero and the capacity %g", initial, capacity))
+	}
+	o := newResourceOptions("Container", opts, "WithFairness")
+	c := &Container{
+		sim:       sim,
+		capacity:  capacity,
+		level:     initial,
+		fairness:  o.fairness,
+		getters:   NewCondition(sim),
+		putters:   NewCondition(sim),
+		gets:      make(map[ConditionActionID]float64),
+		puts:      make(map[ConditionActionID]float64),
+		requested: make(map[ConditionActionID]time.Time),
+	}
+	c.stats = newStatsRecorder(sim, o, capacity, func() (float64, int) {
+		return c.level, c.getters.Waiting() + c.putters.Waiting()
+	})
+	return c
+}
+
+// Stats returns statistics about how the container has been used, where the level counts as being in use and every get served counts as a request served. Returns the zero ResourceStats unless the container was created using WithStats.
+func (c *Container) Stats() ResourceStats {
+	return c.stats.stats()
+}
+
+// Level returns the amount currently held by the container.
+func (c *Container) Level() float64 {
+	return c.level
+}
+
+// Cap returns the capacity of the container, or zero if it is unbounded.
+func (c *Container) Cap() float64 {
+	return max(c.capacity, 0)
+}
+
+// Put puts amount into the container and schedules a to run as soon as possible. If there isn't enough room, amount is put, and a is scheduled, once there is. a may be nil. Panics if amount isn't positive or is more than the capacity, since such a put could never succeed.
+func (c *Container) Put(amount float64, a Action) *Request {
+	c.check(amount)
+	if c.canPut(amount) {
+		c.level += amount
+		if a != nil {
+			c.sim.Immediately(a)
+		}
+		// Wake up the gets after scheduling a, so that the put is seen to happen first.
+		c.dispatch()
+		return &Request{}
+	}
+
+	id := c.putters.Wait(func(sim *Simulation) {
+		if a != nil {
+			a(sim)
+		}
+	})
+	c.puts[id] = amount
+	c.stats.update()
+	return newRequest(func() bool {
+		if !c.putters.Cancel(id) {
+			return false
+		}
+		delete(c.puts, id)
+		c.dispatch()
+		return true
+	})
+}
+
+// Get gets amount from the container and schedules a to run as soon as possible. If the container doesn't hold enough, amount is got, and a is scheduled, once it does. a may be nil. Panics if amount isn't positive or is more than the capacity, since such a get could never succeed.
+func (c *Container) Get(amount float64, a Action) *Request {
+	c.check(amount)
+	if a == nil {
+		// The get still needs an action, so that it can be cancelled until the amount is used.
+		a = func(*Simulation) {}
+	}
+	if c.canGet(amount) {
+		c.level -= amount
+		c.stats.serve(c.sim.Now)
+		event := c.sim.Immediately(a)
+		// Wake up the puts after scheduling a, so that the get is seen to happen first.
+		c.dispatch()
+		return newRequest(func() bool {
+			// The room freed by getting the amount might already have been given to a put, in which case the amount cannot be given back.
+			if !c.hasRoom(amount) || !c.sim.Cancel(event) {
+				return false
+			}
+			// The amount was got, but never used. Give it back.
+			c.level += amount
+			c.dispatch()
+			return true
+		})
+	}
+
+	id := c.getters.Wait(a)
+	c.gets[id] = amount
+	c.requested[id] = c.sim.Now
+	c.stats.update()
+	return newRequest(func() bool {
+		if !c.getters.Cancel(id) {
+			return false
+		}
+		delete(c.gets, id)
+		delete(c.requested, id)
+		c.dispatch()
+		return true
+	})
+}
+
+// TryPut puts amount into the container only if there is room for it right now, without waiting. Returns true if the amount was put.
+func (c *Container) TryPut(amount float64) bool {
+	if amount <= 0 || !c.canPut(amount) {
+		return false
+	}
+	c.level += amount
+	c.dispatch()
+	return true
+}
+
+// TryGet gets amount from the container only if it holds enough right now, without waiting. Returns true if the amount was got.
+func (c *Container) TryGet(amount float64) bool {
+	if amount <= 0 || !c.canGet(amount) {
+		return false
+	}
+	c.level -= amount
+	c.stats.serve(c.sim.Now)
+	c.dispatch()
+	return true
+}
+
+func (c *Container) check(amount float64) {
+	if amount <= 0 || (c.capacity > 0 && amount > c.capacity) {
+		panic(fmt.Sprintf("cannot move %g in or out of a container with capacity %g", amount, c.capacity))
+	}
+}
+
+// hasRoom returns whether amount fits in the container.
+func (c *Container) hasRoom(amount float64) bool {
+	return c.capacity <= 0 || c.level+amount <= c.capacity
+}
+
+// canPut returns whether amount can be put right now, without jumping the queue when using FairnessFIFO.
+func (c *Container) canPut(amount float64) bool {
+	return c.hasRoom(amount) && (c.fairness != FairnessFIFO || c.putters.Waiting() == 0)
+}
+
+// canGet returns whether amount can be got right now, without jumping the queue when using FairnessFIFO.
+func (c *Container) canGet(amount float64) bool {
+	return c.level >= amount && (c.fairness != FairnessFIFO || c.getters.Waiting() == 0)
+}
+
+// dispatch serves waiting gets and puts, according to the fairness policy, until neither can make progress.
+func (c *Container) dispatch() {
+	for progressed := true; progressed; {
+		progressed = false
+		for id := range c.getters.ordered() {
+			amount := c.gets[id]
+			if c.level < amount {
+				if c.fairness == FairnessFIFO {
+					break
+				}
+				continue
+			}
+			c.level -= amount
+			delete(c.gets, id)
+			c.stats.serve(c.requested[id])
+			delete(c.requested, id)
+			c.getters.signalID(id)
+			progressed = true
+		}
+		for id := range c.putters.ordered() {
+			amount := c.puts[id]
+			if !c.hasRoom(amount) {
+				if c.fairness == FairnessFIFO {
+					break
+				}
+				continue
+			}
+			c.level += amount
+			delete(c.puts, id)
+			c.putters.signalID(id)
+			progressed = true
+		}
+	}
+	c.stats.update()
+}
@@ -0,0 +1,144 @@
+package steps
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// ExampleContainer simulates cars refueling at a gas station with a 100 liter tank, which is refilled by a tanker after ten (10) minutes.
+func ExampleContainer() {
+	sim := NewSimulation()
+	tank := NewContainer(sim, 100, 50)
+
+	for i, liters := range []float64{40, 30, 20} {
+		sim.After(time.Duration(i)*time.Minute, func(*Simulation) {
+			tank.Get(liters, func(sim *Simulation) {
+				fmt.Println(sim.Now, "Car", i, "got", liters, "liters")
+			})
+		})
+	}
+	sim.After(10*time.Minute, func(*Simulation) {
+		tank.Put(60, func(sim *Simulation) {
+			fmt.Println(sim.Now, "Refilled 60 liters")
+		})
+	})
+	sim.RunUntilDone()
+	fmt.Println(tank.Level(), "liters left")
+
+	// Output:
+	// 0001-01-01 00:00:00 +0000 UTC Car 0 got 40 liters
+	// 0001-01-01 00:10:00 +0000 UTC Refilled 60 liters
+	// 0001-01-01 00:10:00 +0000 UTC Car 1 got 30 liters
+	// 0001-01-01 00:10:00 +0000 UTC Car 2 got 20 liters
+	// 20 liters left
+}
+
+func TestContainerFairness(t *testing.T) {
+	for _, test := range []struct {
+		fairness Fairness
+		expected []string
+	}{
+		{FairnessFIFO, []string{"1h0m0s big", "1h0m0s small"}},
+		{FairnessFirstFit, []string{"0s small", "1h0m0s big"}},
+	} {
+		sim := NewSimulation()
+		c := NewContainer(sim, 0, 5, WithFairness(test.fairness))
+
+		var order []string
+		get := func(name string, amount float64) {
+			c.Get(amount, func(sim *Simulation) {
+				order = append(order, fmt.Sprint(sim.Now.Sub(time.Time{}), " ", name))
+			})
+		}
+		get("big", 10)
+		get("small", 2)
+		sim.After(time.Hour, func(*Simulation) { c.Put(10, nil) })
+		sim.RunUntilDone()
+
+		if fmt.Sprint(order) != fmt.Sprint(test.expected) {
+			t.Errorf("fairness %d: expected %v, got %v", test.fairness, test.expected, order)
+		}
+		if c.Level() != 3 {
+			t.Errorf("fairness %d: expected level 3, got %g", test.fairness, c.Level())
+		}
+	}
+}
+
+func TestContainerPutWaitsForRoom(t *testing.T) {
+	sim := NewSimulation()
+	c := NewContainer(sim, 10, 8)
+
+	var putAt time.Time
+	c.Put(5, func(sim *Simulation) { putAt = sim.Now })
+	if c.TryPut(1) {
+		t.Error("expected TryPut to not jump the queue")
+	}
+	sim.After(time.Second, func(*Simulation) {
+		if !c.TryGet(3) {
+			t.Error("expected to get 3")
+		}
+	})
+	sim.RunUntilDone()
+
+	if expected := (time.Time{}).Add(time.Second); !putAt.Equal(expected) {
+		t.Errorf("expected the put at %s, got %s", expected, putAt)
+	}
+	if c.Level() != 10 || c.Cap() != 10 {
+		t.Errorf("expected a full container, got level %g and capacity %g", c.Level(), c.Cap())
+	}
+}
+
+func TestContainerCancel(t *testing.T) {
+	sim := NewSimulation()
+	c := NewContainer(sim, 0, 5)
+
+	served := c.Get(5, func(*Simulation) { t.Error("expected the cancelled get to never run") })
+	waiting := c.Get(1, func(*Simulation) { t.Error("expected the cancelled get to never run") })
+	if !waiting.Cancel() || waiting.Cancel() {
+		t.Error("expected the waiting get to be cancelled once")
+	}
+	if !served.Cancel() {
+		t.Error("expected the served get to be cancelled before it ran")
+	}
+	sim.RunUntilDone()
+
+	if c.Level() != 5 {
+		t.Errorf("expected the cancelled get to give back the amount, got level %g", c.Level())
+	}
+}
+
+func TestContainerCancelAfterRoomWasTaken(t *testing.T) {
+	sim := NewSimulation()
+	c := NewContainer(sim, 10, 10)
+
+	put := false
+	c.Put(5, func(*Simulation) { put = true })
+	got := false
+	served := c.Get(5, func(*Simulation) { got = true })
+	if served.Cancel() {
+		t.Error("expected to not cancel the get, since there is no room to give the amount back")
+	}
+	sim.RunUntilDone()
+
+	if !put || !got {
+		t.Errorf("expected both the put and the get to run, got put %v and get %v", put, got)
+	}
+	if c.Level() != 10 {
+		t.Errorf("expected level 10, got %g", c.Level())
+	}
+}
+
+func TestContainerGetWithoutAction(t *testing.T) {
+	sim := NewSimulation()
+	c := NewContainer(sim, 10, 5)
+
+	c.Get(5, nil)
+	c.Get(5, nil)
+	c.Put(5, nil)
+	sim.RunUntilDone()
+
+	if c.Level() != 0 {
+		t.Errorf("expected level 0, got %g", c.Level())
+	}
+}
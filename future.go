@@ -0,0 +1,178 @@
+package steps
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrPending is returned by Future.Result if the future has not been resolved yet.
+var ErrPending = errors.New("future is not resolved yet")
+
+// ErrTimeout is the error of a future returned by WithTimeout that timed out.
+var ErrTimeout = errors.New("timed out")
+
+// Future is a one-shot event in a simulation that eventually either succeeds with a value or fails with an error. Unlike a Condition, a future remembers that it has been resolved, so actions waiting for a future that is already resolved are run right away.
+type Future[T any] struct {
+	sim *Simulation
+
+	resolved bool
+	value    T
+	err      error
+
+	// waiting holds the actions waiting for the future to be resolved.
+	waiting *Condition
+}
+
+// NewFuture creates a new future that has not been resolved.
+func NewFuture[T any](sim *Simulation) *Future[T] {
+	return &Future[T]{
+		sim:     sim,
+		waiting: NewCondition(sim),
+	}
+}
+
+// Succeed resolves the future with a value, and schedules all actions waiting for the future to run as soon as possible. Returns false, and does nothing, if the future has already been resolved.
+func (f *Future[T]) Succeed(value T) bool {
+	return f.resolve(value, nil)
+}
+
+// Fail resolves the future with an error, and schedules all actions waiting for the future to run as soon as possible. Returns false, and does nothing, if the future has already been resolved. Panics if err is nil.
+func (f *Future[T]) Fail(err error) bool {
+	if err == nil {
+		panic("a future cannot fail with a nil error")
+	}
+	var zero T
+	return f.resolve(zero, err)
+}
+
+func (f *Future[T]) resolve(value T, err error) bool {
+	if f.resolved {
+		return false
+	}
+	f.resolved = true
+	f.value, f.err = value, err
+	f.waiting.Broadcast()
+	return true
+}
+
+// Done returns true if the future has been resolved.
+func (f *Future[T]) Done() bool {
+	return f.resolved
+}
+
+// Result returns the value the future succeeded with, or the error it failed with. Returns ErrPending if the future has not been resolved yet.
+func (f *Future[T]) Result() (T, error) {
+	if !f.resolved {
+		var zero T
+		return zero, ErrPending
+	}
+	return f.value, f.err
+}
+
+// Then makes a wait until the future is resolved. If the future is already resolved, a is run as soon as possible. Use Result to find out how the future was resolved. It returns an ID that can be used to cancel the wait (see Cancel).
+func (f *Future[T]) Then(a Action) ConditionActionID {
+	id := f.waiting.Wait(a)
+	if f.resolved {
+		// Everyone else waiting has already been woken up, so this only wakes up a.
+		f.waiting.Broadcast()
+	}
+	return id
+}
+
+// Cancel cancels an action waiting for the future. Returns true if the action was found and removed, false otherwise (e.g. the future was resolved and the action has already been scheduled, or it was previously cancelled).
+func (f *Future[T]) Cancel(id ConditionActionID) bool {
+	return f.waiting.Cancel(id)
+}
+
+// AllOf returns a future that succeeds with the values of all futures, in the same order, once all of them have succeeded. If any of the futures fails, the returned future fails with the same error right away and stops waiting for the others. AllOf of no futures succeeds as soon as possible.
+func AllOf[T any](sim *Simulation, futures ...*Future[T]) *Future[[]T] {
+	all := NewFuture[[]T](sim)
+	if len(futures) == 0 {
+		sim.Immediately(func(*Simulation) { all.Succeed([]T{}) })
+		return all
+	}
+
+	ids := make([]ConditionActionID, len(futures))
+	remaining := len(futures)
+	for i, f := range futures {
+		ids[i] = f.Then(func(*Simulation) {
+			if all.Done() {
+				return
+			}
+			if _, err := f.Result(); err != nil {
+				all.Fail(err)
+				cancelOthers(futures, ids, i)
+				return
+			}
+			remaining--
+			if remaining == 0 {
+				values := make([]T, len(futures))
+				for j, f := range futures {
+					values[j], _ = f.Result()
+				}
+				all.Succeed(values)
+			}
+		})
+	}
+	return all
+}
+
+// AnyOf returns a future that is resolved like the first of the futures to be resolved, whether it succeeded or failed. Once resolved, it stops waiting for the other futures. If several futures are resolved at the same time, the first one to be resolved wins. AnyOf of no futures is never resolved.
+func AnyOf[T any](sim *Simulation, futures ...*Future[T]) *Future[T] {
+	first := NewFuture[T](sim)
+	ids := make([]ConditionActionID, len(futures))
+	for i, f := range futures {
+		ids[i] = f.Then(func(*Simulation) {
+			if first.Done() {
+				return
+			}
+			first.resolve(f.value, f.err)
+			cancelOthers(futures, ids, i)
+		})
+	}
+	return first
+}
+
+// cancelOthers cancels the waits with the given IDs for all futures but the one at index winner.
+func cancelOthers[T any](futures []*Future[T], ids []ConditionActionID, winner int) {
+	for i, f := range futures {
+		if i != winner {
+			f.Cancel(ids[i])
+		}
+	}
+}
+
+// WithTimeout returns a future that is resolved like f, unless f is not resolved within the duration d, in which case it fails with ErrTimeout. Whichever happens last is cancelled. Note that f itself is left as is when timing out; use it to cancel whatever f was waiting for, if needed.
+func WithTimeout[T any](f *Future[T], d time.Duration) *Future[T] {
+	sim := f.sim
+	timed := NewFuture[T](sim)
+	var id ConditionActionID
+	timer := sim.After(d, func(*Simulation) {
+		if timed.Fail(ErrTimeout) {
+			f.Cancel(id)
+		}
+	})
+	id = f.Then(func(*Simulation) {
+		if timed.resolve(f.value, f.err) {
+			sim.Cancel(timer)
+		}
+	})
+	return timed
+}
+
+// Await returns a command that makes a process wait until f is resolved. Use Future.Result to find out how it was resolved.
+func Await[T any](f *Future[T]) Command {
+	return awaitCommand[T]{f: f}
+}
+
+type awaitCommand[T any] struct {
+	f *Future[T]
+}
+
+func (c awaitCommand[T]) start(p *Process) {
+	p.waitID = c.f.Then(p.resume)
+}
+
+func (c awaitCommand[T]) cancel(p *Process) bool {
+	return c.f.Cancel(p.waitID)
+}
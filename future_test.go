@@ -0,0 +1,197 @@
+package steps
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// ExampleFuture demonstrates how to wait for whichever of two things happens first.
+func ExampleFuture() {
+	sim := NewSimulation()
+
+	reply := NewFuture[string](sim)
+	sim.After(5*time.Second, func(*Simulation) { reply.Succeed("pong") })
+
+	NewProcess(sim, func(p *Process, yield func(Command) bool) {
+		for _, timeout := range []time.Duration{time.Second, 10 * time.Second} {
+			f := WithTimeout(reply, timeout)
+			if !yield(Await(f)) {
+				return
+			}
+			value, err := f.Result()
+			fmt.Println(p.Sim().Now, value, err)
+		}
+	})
+	sim.RunUntilDone()
+
+	// Output:
+	// 0001-01-01 00:00:01 +0000 UTC  timed out
+	// 0001-01-01 00:00:05 +0000 UTC pong <nil>
+}
+
+func TestFutureResolvesOnce(t *testing.T) {
+	sim := NewSimulation()
+	f := NewFuture[int](sim)
+
+	if _, err := f.Result(); !errors.Is(err, ErrPending) {
+		t.Errorf("expected %v, got %v", ErrPending, err)
+	}
+	var results []string
+	record := func(*Simulation) {
+		value, err := f.Result()
+		results = append(results, fmt.Sprint(value, " ", err))
+	}
+	f.Then(record)
+	cancelled := f.Then(record)
+	if !f.Cancel(cancelled) {
+		t.Error("expected the wait to be cancelled")
+	}
+
+	if !f.Succeed(42) {
+		t.Error("expected the future to be resolved")
+	}
+	if f.Succeed(43) || f.Fail(errors.New("too late")) {
+		t.Error("expected the future to only be resolved once")
+	}
+	sim.RunUntilDone()
+
+	// Waiting for a future that is already resolved runs the action right away.
+	f.Then(record)
+	sim.RunUntilDone()
+
+	if expected := []string{"42 <nil>", "42 <nil>"}; fmt.Sprint(results) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, results)
+	}
+	if !f.Done() {
+		t.Error("expected the future to be done")
+	}
+}
+
+func TestFutureFail(t *testing.T) {
+	sim := NewSimulation()
+	f := NewFuture[int](sim)
+	errBroken := errors.New("broken")
+	f.Fail(errBroken)
+
+	if value, err := f.Result(); value != 0 || !errors.Is(err, errBroken) {
+		t.Errorf("expected 0 and %v, got %d and %v", errBroken, value, err)
+	}
+}
+
+func TestAllOf(t *testing.T) {
+	sim := NewSimulation()
+	futures := []*Future[int]{NewFuture[int](sim), NewFuture[int](sim), NewFuture[int](sim)}
+	all := AllOf(sim, futures...)
+	for i, f := range futures {
+		sim.After(time.Duration(3-i)*time.Second, func(*Simulation) { f.Succeed(i) })
+	}
+
+	var doneAt time.Time
+	all.Then(func(s *Simulation) { doneAt = s.Now })
+	sim.RunUntilDone()
+
+	values, err := all.Result()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if expected := []int{0, 1, 2}; fmt.Sprint(values) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+	if expected := (time.Time{}).Add(3 * time.Second); !doneAt.Equal(expected) {
+		t.Errorf("expected to be done at %s, got %s", expected, doneAt)
+	}
+}
+
+func TestAllOfFailsFast(t *testing.T) {
+	sim := NewSimulation()
+	slow := NewFuture[int](sim)
+	failing := NewFuture[int](sim)
+	all := AllOf(sim, slow, failing)
+
+	errBroken := errors.New("broken")
+	sim.After(time.Second, func(*Simulation) { failing.Fail(errBroken) })
+	sim.After(time.Hour, func(*Simulation) { slow.Succeed(1) })
+
+	var doneAt time.Time
+	all.Then(func(s *Simulation) { doneAt = s.Now })
+	sim.RunUntilDone()
+
+	if _, err := all.Result(); !errors.Is(err, errBroken) {
+		t.Errorf("expected %v, got %v", errBroken, err)
+	}
+	if expected := (time.Time{}).Add(time.Second); !doneAt.Equal(expected) {
+		t.Errorf("expected to be done at %s, got %s", expected, doneAt)
+	}
+	if slow.waiting.Waiting() != 0 {
+		t.Error("expected the wait for the slow future to be cancelled")
+	}
+}
+
+func TestAllOfNothing(t *testing.T) {
+	sim := NewSimulation()
+	all := AllOf[int](sim)
+	sim.RunUntilDone()
+
+	if values, err := all.Result(); err != nil || len(values) != 0 {
+		t.Errorf("expected no values and no error, got %v and %v", values, err)
+	}
+}
+
+func TestAnyOf(t *testing.T) {
+	sim := NewSimulation()
+	futures := []*Future[string]{NewFuture[string](sim), NewFuture[string](sim), NewFuture[string](sim)}
+	first := AnyOf(sim, futures...)
+	sim.After(2*time.Second, func(*Simulation) { futures[0].Succeed("slow") })
+	sim.After(time.Second, func(*Simulation) {
+		// The first future to be resolved wins, even if another one is resolved at the same time.
+		futures[2].Succeed("fast 2")
+		futures[1].Succeed("fast 1")
+	})
+	sim.RunUntilDone()
+
+	if value, err := first.Result(); value != "fast 2" || err != nil {
+		t.Errorf("expected fast 2 and no error, got %s and %v", value, err)
+	}
+	if futures[0].waiting.Waiting() != 0 {
+		t.Error("expected the wait for the losing future to be cancelled")
+	}
+}
+
+func TestWithTimeoutCancelsTimer(t *testing.T) {
+	sim := NewSimulation()
+	f := NewFuture[int](sim)
+	timed := WithTimeout(f, time.Hour)
+	sim.After(time.Second, func(*Simulation) { f.Succeed(1) })
+	sim.RunUntilDone()
+
+	if value, err := timed.Result(); value != 1 || err != nil {
+		t.Errorf("expected 1 and no error, got %d and %v", value, err)
+	}
+	if expected := (time.Time{}).Add(time.Second); !sim.Now.Equal(expected) {
+		t.Errorf("expected the timer to be cancelled and the clock to be at %s, got %s", expected, sim.Now)
+	}
+}
+
+func TestProcessAwaitInterrupted(t *testing.T) {
+	sim := NewSimulation()
+	f := NewFuture[int](sim)
+
+	var interrupted bool
+	p := NewProcess(sim, func(p *Process, yield func(Command) bool) {
+		if !yield(Await(f)) {
+			return
+		}
+		_, interrupted = p.Interrupted()
+	})
+	sim.After(time.Second, func(*Simulation) { p.Interrupt(nil) })
+	sim.RunUntilDone()
+
+	if !interrupted || !p.Done() {
+		t.Error("expected the process to be interrupted")
+	}
+	if f.waiting.Waiting() != 0 {
+		t.Error("expected the wait for the future to be cancelled")
+	}
+}
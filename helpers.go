@@ -9,9 +9,9 @@ func Ticker(sim *Simulation, start time.Time, duration time.Duration, f Action)
 	var nextRun func(s *Simulation)
 	nextRun = func(s *Simulation) {
 		f(s)
-		s.Schedule(Event{When: s.Now.Add(duration), Action: nextRun})
+		s.After(duration, nextRun)
 	}
 
 	// Schedule the first run.
-	sim.Schedule(Event{When: start, Action: nextRun})
+	sim.At(start, nextRun)
 }
@@ -0,0 +1,242 @@
+package steps
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	// ladderThreshold is the maximum number of events in a rung bucket that are sorted directly into the bottom. Larger buckets are spawned into a new rung.
+	ladderThreshold = 50
+
+	// ladderMaxRungs is the maximum number of rungs in the ladder.
+	ladderMaxRungs = 8
+)
+
+// NewLadderQueue creates a ladder queue[1] based Queue. A ladder queue keeps far-future events unsorted and only sorts events once they get close to being processed, which gives amortized O(1) push and pop even for skewed distributions of event times.
+//
+// [1]: https://doi.org/10.1145/1103323.1103324
+func NewLadderQueue() Queue {
+	return &ladderQueue{
+		topStart: math.MinInt64,
+		events:   newTombstones(),
+	}
+}
+
+// ladderQueue is a ladder queue. Removed events are lazily dropped when they reach the bottom of the ladder.
+type ladderQueue struct {
+	// origin is the time of the first event pushed, used to compute event keys.
+	origin    time.Time
+	hasOrigin bool
+
+	// top holds unsorted events with keys from topStart and up.
+	top            []ScheduledEvent
+	topMin, topMax int64
+	topStart       int64
+
+	// rungs holds events partially sorted into buckets. Every rung covers the range of the current bucket of the rung above it.
+	rungs []*ladderRung
+
+	// bottom holds the events that are next to be processed, sorted in reverse order so that the next event is last.
+	bottom []ScheduledEvent
+
+	// far holds the events whose keys saturated, since they are more than ~292 years after origin. They come after all other events, and are moved into the ladder using a new origin once the rest of the ladder is empty.
+	far []ScheduledEvent
+
+	events tombstones
+}
+
+// ladderRung is a rung in a ladder queue.
+type ladderRung struct {
+	buckets [][]ScheduledEvent
+	start   int64
+	width   int64
+
+	// current is the index of the first bucket that may contain events.
+	current int
+}
+
+// currentStart returns the smallest key that can be stored in the rung.
+func (r *ladderRung) currentStart() int64 {
+	return r.start + int64(r.current)*r.width
+}
+
+// add puts an event in its bucket.
+func (r *ladderRung) add(e ScheduledEvent, key int64) {
+	i := int((uint64(key) - uint64(r.start)) / uint64(r.width))
+	r.buckets[i] = append(r.buckets[i], e)
+}
+
+// Push adds an event to the queue.
+func (q *ladderQueue) Push(e ScheduledEvent) {
+	q.events.add(e)
+	if !q.hasOrigin {
+		q.origin = e.Event.When
+		q.hasOrigin = true
+	}
+	q.place(e)
+}
+
+// place puts an event on the ladder, according to its key.
+func (q *ladderQueue) place(e ScheduledEvent) {
+	key := eventKey(q.origin, e.Event.When)
+	if key == math.MaxInt64 {
+		q.far = append(q.far, e)
+		return
+	}
+	if key >= q.topStart {
+		if len(q.top) == 0 || key < q.topMin {
+			q.topMin = key
+		}
+		if len(q.top) == 0 || key > q.topMax {
+			q.topMax = key
+		}
+		q.top = append(q.top, e)
+		return
+	}
+	for _, r := range q.rungs {
+		if key >= r.currentStart() {
+			r.add(e, key)
+			return
+		}
+	}
+	index, _ := slices.BinarySearchFunc(q.bottom, e, func(a, b ScheduledEvent) int {
+		return -compareEvents(a, b)
+	})
+	q.bottom = slices.Insert(q.bottom, index, e)
+}
+
+// Pop removes the next event from the queue.
+func (q *ladderQueue) Pop() ScheduledEvent {
+	for {
+		q.fill()
+		e := q.bottom[len(q.bottom)-1]
+		q.bottom = q.bottom[:len(q.bottom)-1]
+		if q.events.forget(e) {
+			return e
+		}
+	}
+}
+
+// Peek returns the next event in the queue without removing it.
+func (q *ladderQueue) Peek() ScheduledEvent {
+	for {
+		q.fill()
+		e := q.bottom[len(q.bottom)-1]
+		if q.events.isLive(e) {
+			return e
+		}
+		// Drop the removed event so that we don't have to skip it again.
+		q.bottom = q.bottom[:len(q.bottom)-1]
+		q.events.forget(e)
+	}
+}
+
+// Remove removes an event from the queue. Returns true if the event was found and removed, false otherwise.
+func (q *ladderQueue) Remove(id EventID) bool {
+	return q.events.remove(id)
+}
+
+// Len returns the number of events in the queue.
+func (q *ladderQueue) Len() int {
+	return q.events.len()
+}
+
+// Lookup returns the event with the given ID.
+func (q *ladderQueue) Lookup(id EventID) (ScheduledEvent, bool) {
+	return q.events.lookup(id)
+}
+
+// Update replaces the event that has the same ID as e with e. The old event is left in the queue, to be skipped when it is popped.
+func (q *ladderQueue) Update(e ScheduledEvent) bool {
+	if !q.events.remove(e.ID) {
+		return false
+	}
+	q.Push(e)
+	return true
+}
+
+// fill makes sure the bottom is non-empty by moving events down the ladder. It must only be called if the queue is non-empty.
+func (q *ladderQueue) fill() {
+	for len(q.bottom) == 0 {
+		if len(q.rungs) == 0 && len(q.top) == 0 {
+			q.rebase()
+			continue
+		}
+		if len(q.rungs) == 0 {
+			q.fillFromTop()
+			continue
+		}
+
+		r := q.rungs[len(q.rungs)-1]
+		for r.current < len(r.buckets) && len(r.buckets[r.current]) == 0 {
+			r.current++
+		}
+		if r.current == len(r.buckets) {
+			// The rung is exhausted.
+			q.rungs = q.rungs[:len(q.rungs)-1]
+			continue
+		}
+
+		bucket := r.buckets[r.current]
+		r.buckets[r.current] = nil
+		bucketStart := r.currentStart()
+		r.current++
+		if len(bucket) > ladderThreshold && r.width > 1 && len(q.rungs) < ladderMaxRungs {
+			q.spawn(bucket, bucketStart, r.width)
+		} else {
+			q.sortIntoBottom(bucket)
+		}
+	}
+}
+
+// fillFromTop moves the events in the top into a new rung, or directly into the bottom if there are only a few of them.
+func (q *ladderQueue) fillFromTop() {
+	top := q.top
+	q.top = nil
+	if len(top) <= ladderThreshold || q.topMin == q.topMax {
+		// Keys in the top never saturate, so this cannot overflow.
+		q.topStart = q.topMax + 1
+		q.sortIntoBottom(top)
+		return
+	}
+
+	r := q.spawn(top, q.topMin, q.topMax-q.topMin+1)
+	q.topStart = r.start + int64(len(r.buckets))*r.width
+}
+
+// rebase moves the far events into the ladder, which must otherwise be empty, using the earliest of them as the new origin.
+func (q *ladderQueue) rebase() {
+	far := q.far
+	q.far = nil
+	q.origin = slices.MinFunc(far, compareEvents).Event.When
+	q.topStart = math.MinInt64
+	for _, e := range far {
+		q.place(e)
+	}
+}
+
+// spawn creates a new rung covering span keys starting from start and distributes events into it.
+func (q *ladderQueue) spawn(events []ScheduledEvent, start int64, span int64) *ladderRung {
+	width := max(1, int64(uint64(span)/uint64(len(events))))
+	nbuckets := int((uint64(span) + uint64(width) - 1) / uint64(width))
+	r := &ladderRung{
+		buckets: make([][]ScheduledEvent, nbuckets),
+		start:   start,
+		width:   width,
+	}
+	for _, e := range events {
+		r.add(e, eventKey(q.origin, e.Event.When))
+	}
+	q.rungs = append(q.rungs, r)
+	return r
+}
+
+// sortIntoBottom sorts events into the bottom, which must be empty.
+func (q *ladderQueue) sortIntoBottom(events []ScheduledEvent) {
+	slices.SortFunc(events, func(a, b ScheduledEvent) int {
+		return -compareEvents(a, b)
+	})
+	q.bottom = events
+}
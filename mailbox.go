@@ -0,0 +1,112 @@
+package steps
+
+import "time"
+
+// Overflow decides what happens when a message is sent to a full Mailbox. See WithOverflow.
+type Overflow int
+
+const (
+	// OverflowBlock makes the sender wait until there is room for the message. This is the default.
+	OverflowBlock Overflow = iota
+
+	// OverflowDropNewest drops the message being sent.
+	OverflowDropNewest
+
+	// OverflowDropOldest drops the oldest message in the mailbox to make room for the one being sent.
+	OverflowDropOldest
+)
+
+// WithOverflow sets what happens when a message is sent to a full Mailbox. The default is OverflowBlock. It only applies to a Mailbox.
+func WithOverflow(o Overflow) ResourceOption {
+	return func(opts *resourceOptions) {
+		opts.overflow = o
+		opts.given = append(opts.given, "WithOverflow")
+	}
+}
+
+// Mailbox passes messages between entities of a simulation, such as actors or the producers and consumers of a message queue. Messages are received in the order they were delivered, unless received selectively using ReceiveMatching. A mailbox has an optional capacity, and what happens when a message is sent to a full mailbox is decided by WithOverflow.
+type Mailbox[T any] struct {
+	messages store[T]
+	overflow Overflow
+	dropped  int
+}
+
+// NewMailbox creates a new, empty, mailbox that can hold capacity messages. A capacity of zero or less means the mailbox is unbounded, so sending a message never waits nor drops anything.
+func NewMailbox[T any](sim *Simulation, capacity int, opts ...ResourceOption) *Mailbox[T] {
+	o := newResourceOptions("Mailbox", opts, "WithOverflow")
+	m := &Mailbox[T]{overflow: o.overflow}
+	m.messages.filtered = true
+	m.messages.init(sim, capacity, &fifoItems[T]{}, o)
+	return m
+}
+
+// Send delivers msg to the mailbox and schedules a to run as soon as possible. a may be nil. If the mailbox is full, what happens depends on the overflow policy: when using OverflowBlock, msg is delivered, and a is scheduled, once there is room. Otherwise, a is scheduled right away, even if a message was dropped.
+func (m *Mailbox[T]) Send(msg T, a Action) *Request {
+	if m.messages.hasRoom() || m.overflow == OverflowBlock {
+		return m.messages.Put(msg, a)
+	}
+	if a != nil {
+		m.messages.sim.Immediately(a)
+	}
+	m.dropped++
+	if m.overflow == OverflowDropOldest && m.messages.items.len() > 0 {
+		m.messages.items.take(nil)
+		m.messages.items.push(msg)
+		m.messages.dispatch()
+	}
+	return &Request{}
+}
+
+// SendAfter is like Send, but delivers msg after the given delay, which is useful to model the latency of a network. Cancelling the returned request before msg has been delivered means that it will never be.
+func (m *Mailbox[T]) SendAfter(d time.Duration, msg T, a Action) *Request {
+	var send *Request
+	event := m.messages.sim.After(d, func(*Simulation) {
+		send = m.Send(msg, a)
+	})
+	return newRequest(func() bool {
+		if send != nil {
+			return send.Cancel()
+		}
+		return m.messages.sim.Cancel(event)
+	})
+}
+
+// Receive receives the next message from the mailbox and passes it to a. If the mailbox is empty, a will be scheduled to run once a message is delivered.
+func (m *Mailbox[T]) Receive(a func(*Simulation, T)) *Request {
+	return m.messages.get(nil, a)
+}
+
+// ReceiveMatching receives the first message in the mailbox for which match returns true and passes it to a. If there is no such message, a will be scheduled to run once one is delivered. Messages that don't match are left in the mailbox for other receives.
+func (m *Mailbox[T]) ReceiveMatching(match func(T) bool, a func(*Simulation, T)) *Request {
+	return m.messages.get(match, a)
+}
+
+// TryReceive receives the next message from the mailbox only if there is one right now, without waiting.
+func (m *Mailbox[T]) TryReceive() (T, bool) {
+	return m.messages.tryGet(nil)
+}
+
+// TryReceiveMatching receives the first message in the mailbox for which match returns true, only if there is one right now, without waiting.
+func (m *Mailbox[T]) TryReceiveMatching(match func(T) bool) (T, bool) {
+	return m.messages.tryGet(match)
+}
+
+// Len returns the number of messages in the mailbox.
+func (m *Mailbox[T]) Len() int {
+	return m.messages.Len()
+}
+
+// Cap returns the capacity of the mailbox, or zero if it is unbounded.
+func (m *Mailbox[T]) Cap() int {
+	return m.messages.Cap()
+}
+
+// Dropped returns the number of messages dropped because the mailbox was full. See WithOverflow.
+func (m *Mailbox[T]) Dropped() int {
+	return m.dropped
+}
+
+// Stats returns statistics about how the mailbox has been used, where the messages held count as being in use and every message received counts as a request served. Returns the zero ResourceStats unless the mailbox was created using WithStats.
+func (m *Mailbox[T]) Stats() ResourceStats {
+	return m.messages.Stats()
+}
@@ -0,0 +1,126 @@
+package steps
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// ExampleMailbox simulates two actors playing ping-pong over a network with a latency of ten (10) milliseconds.
+func ExampleMailbox() {
+	sim := NewSimulation()
+	ping := NewMailbox[int](sim, 0)
+	pong := NewMailbox[int](sim, 0)
+
+	var player func(name string, in, out *Mailbox[int])
+	player = func(name string, in, out *Mailbox[int]) {
+		in.Receive(func(sim *Simulation, ball int) {
+			fmt.Println(sim.Now, name, ball)
+			if ball < 3 {
+				out.SendAfter(10*time.Millisecond, ball+1, nil)
+				player(name, in, out)
+			}
+		})
+	}
+	player("Ping", ping, pong)
+	player("Pong", pong, ping)
+	ping.Send(0, nil)
+	sim.RunUntilDone()
+
+	// Output:
+	// 0001-01-01 00:00:00 +0000 UTC Ping 0
+	// 0001-01-01 00:00:00.01 +0000 UTC Pong 1
+	// 0001-01-01 00:00:00.02 +0000 UTC Ping 2
+	// 0001-01-01 00:00:00.03 +0000 UTC Pong 3
+}
+
+func TestMailboxOverflow(t *testing.T) {
+	for _, test := range []struct {
+		overflow Overflow
+		expected []int
+		dropped  int
+	}{
+		{OverflowBlock, []int{1, 2, 3, 4}, 0},
+		{OverflowDropNewest, []int{1, 2}, 2},
+		{OverflowDropOldest, []int{3, 4}, 2},
+	} {
+		sim := NewSimulation()
+		m := NewMailbox[int](sim, 2, WithOverflow(test.overflow))
+
+		sent := 0
+		for i := 1; i <= 4; i++ {
+			m.Send(i, func(*Simulation) { sent++ })
+		}
+		sim.RunUntilDone()
+		if test.overflow != OverflowBlock && sent != 4 {
+			t.Errorf("overflow %d: expected all 4 senders to continue, got %d", test.overflow, sent)
+		}
+
+		var received []int
+		for range 4 {
+			m.Receive(func(_ *Simulation, msg int) { received = append(received, msg) })
+		}
+		sim.RunUntilDone()
+
+		if fmt.Sprint(received) != fmt.Sprint(test.expected) {
+			t.Errorf("overflow %d: expected %v, got %v", test.overflow, test.expected, received)
+		}
+		if m.Dropped() != test.dropped {
+			t.Errorf("overflow %d: expected %d dropped, got %d", test.overflow, test.dropped, m.Dropped())
+		}
+		if sent != 4 {
+			t.Errorf("overflow %d: expected 4 senders to continue, got %d", test.overflow, sent)
+		}
+	}
+}
+
+func TestMailboxReceiveMatching(t *testing.T) {
+	sim := NewSimulation()
+	m := NewMailbox[string](sim, 0)
+
+	var order []string
+	m.ReceiveMatching(func(msg string) bool { return msg == "urgent" }, func(_ *Simulation, msg string) {
+		order = append(order, "matching "+msg)
+	})
+	m.Send("normal", nil)
+	m.Send("urgent", nil)
+	sim.RunUntilDone()
+	m.Receive(func(_ *Simulation, msg string) {
+		order = append(order, "any "+msg)
+	})
+	sim.RunUntilDone()
+
+	expected := []string{"matching urgent", "any normal"}
+	if fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, order)
+	}
+	if _, ok := m.TryReceiveMatching(func(string) bool { return true }); ok {
+		t.Error("expected the mailbox to be empty")
+	}
+}
+
+func TestMailboxSendAfter(t *testing.T) {
+	sim := NewSimulation()
+	m := NewMailbox[int](sim, 0)
+
+	var receivedAt time.Duration
+	m.Receive(func(sim *Simulation, msg int) {
+		receivedAt = sim.Now.Sub(time.Time{})
+	})
+	m.SendAfter(time.Second, 1, nil)
+	cancelled := m.SendAfter(time.Millisecond, 2, nil)
+	if !cancelled.Cancel() {
+		t.Error("expected to cancel the delivery")
+	}
+	sim.RunUntilDone()
+
+	if receivedAt != time.Second {
+		t.Errorf("expected to receive after 1s, got %v", receivedAt)
+	}
+	if m.Len() != 0 {
+		t.Errorf("expected the cancelled message to never be delivered, got %d messages", m.Len())
+	}
+	if cancelled.Cancel() {
+		t.Error("expected a second cancel to fail")
+	}
+}
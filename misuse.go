@@ -0,0 +1,129 @@
+package steps
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrResourceMisuse is the error reported when a resource is misused, for example when a semaphore is released more times than it has been acquired. See MisusePolicy.
+var ErrResourceMisuse = errors.New("resource misuse")
+
+// MisusePolicy decides what happens when a resource is misused, which is usually a bug in the model. See WithMisusePolicy.
+type MisusePolicy int
+
+const (
+	// MisuseIgnore ignores misuse. The misuse itself has no effect, for example releasing a semaphore that isn't held doesn't free up any more units. This is the default.
+	MisuseIgnore MisusePolicy = iota
+
+	// MisusePanic panics with an error wrapping ErrResourceMisuse.
+	MisusePanic
+
+	// MisuseReport ignores misuse like MisuseIgnore, but counts it (see Simulation.Misuses) and calls the OnMisuse hook.
+	MisuseReport
+)
+
+// misuse handles a misused resource according to the MisusePolicy of the simulation.
+func (s *Simulation) misuse(format string, args ...any) {
+	if s.misusePolicy == MisuseIgnore {
+		return
+	}
+	err := fmt.Errorf("%w: %s", ErrResourceMisuse, fmt.Sprintf(format, args...))
+	if s.misusePolicy == MisusePanic {
+		panic(err)
+	}
+	s.misuses++
+	for _, h := range s.hooks {
+		if h.OnMisuse != nil {
+			h.OnMisuse(s, err)
+		}
+	}
+}
+
+// Misuses returns the number of times a resource has been misused using the MisuseReport policy.
+func (s *Simulation) Misuses() int {
+	return s.misuses
+}
+
+// LeakReport lists acquisitions that still hold units of a resource, and conditions that actions are still waiting for. At the end of a run, these usually mean that the model forgot to release a resource or to signal a condition. See WithLeakTracking.
+type LeakReport struct {
+	// Held lists the acquisitions that hold units, in the order they were handed them.
+	Held []LeakedAcquisition
+
+	// Blocked lists the conditions that actions are waiting for, in the order the conditions were created. This includes the conditions used internally by resources, such as the one a CountingSemaphore uses for actions waiting to acquire it.
+	Blocked []BlockedCondition
+}
+
+// LeakedAcquisition is an acquisition that holds units of a resource.
+type LeakedAcquisition struct {
+	Acquisition *Acquisition
+
+	// Units is the number of units held, since the given time.
+	Units int
+	Since time.Time
+}
+
+// BlockedCondition is a condition that actions are waiting for.
+type BlockedCondition struct {
+	Condition *Condition
+
+	// Waiting is the number of actions waiting.
+	Waiting int
+}
+
+// Empty returns true if nothing is held or waiting.
+func (r LeakReport) Empty() bool {
+	return len(r.Held) == 0 && len(r.Blocked) == 0
+}
+
+// String returns a human-readable summary of the report, with one line per leak.
+func (r LeakReport) String() string {
+	if r.Empty() {
+		return "no leaks"
+	}
+	var lines []string
+	for _, h := range r.Held {
+		lines = append(lines, fmt.Sprintf("%d units held since %s", h.Units, h.Since))
+	}
+	for _, c := range r.Blocked {
+		lines = append(lines, fmt.Sprintf("%d actions waiting for a condition", c.Waiting))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// LeakReport returns what is currently held or waiting in the simulation. It is meant to be called at the end of a run. Returns an empty report unless the simulation was created using WithLeakTracking.
+func (s *Simulation) LeakReport() LeakReport {
+	var r LeakReport
+	for _, acq := range s.held {
+		r.Held = append(r.Held, LeakedAcquisition{Acquisition: acq, Units: acq.held, Since: acq.since})
+	}
+	for _, c := range s.conditions {
+		if n := c.Waiting(); n > 0 {
+			r.Blocked = append(r.Blocked, BlockedCondition{Condition: c, Waiting: n})
+		}
+	}
+	return r
+}
+
+// trackCondition keeps track of a condition for the leak report.
+func (s *Simulation) trackCondition(c *Condition) {
+	if s.trackLeaks {
+		s.conditions = append(s.conditions, c)
+	}
+}
+
+// trackHeld keeps track of an acquisition that has been handed units, for the leak report.
+func (s *Simulation) trackHeld(acq *Acquisition) {
+	if s.trackLeaks {
+		s.held = append(s.held, acq)
+	}
+}
+
+// untrackHeld stops keeping track of an acquisition that no longer holds any units.
+func (s *Simulation) untrackHeld(acq *Acquisition) {
+	if i := slices.Index(s.held, acq); i >= 0 {
+		s.held = slices.Delete(s.held, i, i+1)
+	}
+}
@@ -0,0 +1,175 @@
+package steps
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// ExampleSimulation_LeakReport demonstrates how to find a forgotten release at the end of a run.
+func ExampleSimulation_LeakReport() {
+	sim := NewSimulation(WithLeakTracking())
+	sem := NewBinarySemaphore(sim)
+
+	sem.Acquire(func(sim *Simulation) {
+		// Oops, forgot to release the semaphore.
+	})
+	sim.After(time.Second, func(*Simulation) {
+		sem.Acquire(func(*Simulation) {})
+	})
+	sim.RunUntilDone()
+
+	fmt.Println(sim.LeakReport())
+
+	// Output:
+	// 1 units held since 0001-01-01 00:00:00 +0000 UTC
+	// 1 actions waiting for a condition
+}
+
+func TestAcquisitionReleasedTwicePanics(t *testing.T) {
+	sim := NewSimulation(WithMisusePolicy(MisusePanic))
+	sem := NewCountingSemaphore(sim, 2)
+
+	acq := sem.Acquire(func(*Simulation) {})
+	sim.RunUntilDone()
+	acq.Release()
+
+	defer func() {
+		err, ok := recover().(error)
+		if !ok || !errors.Is(err, ErrResourceMisuse) {
+			t.Errorf("expected a panic with %v, got %v", ErrResourceMisuse, err)
+		}
+	}()
+	acq.Release()
+}
+
+func TestReleaseWithoutAcquireIsReported(t *testing.T) {
+	var reported []error
+	sim := NewSimulation(WithMisusePolicy(MisuseReport), WithHooks(Hooks{
+		OnMisuse: func(_ *Simulation, err error) { reported = append(reported, err) },
+	}))
+	sem := NewBinarySemaphore(sim)
+	sem.Release()
+
+	// The extra release must not allow two holders at once.
+	running, maxRunning := 0, 0
+	for range 2 {
+		sem.Acquire(func(sim *Simulation) {
+			running++
+			maxRunning = max(maxRunning, running)
+			sim.After(time.Second, func(*Simulation) {
+				running--
+				sem.Release()
+			})
+		})
+	}
+	sim.RunUntilDone()
+
+	if sim.Misuses() != 1 || len(reported) != 1 || !errors.Is(reported[0], ErrResourceMisuse) {
+		t.Errorf("expected one misuse to be reported, got %d and %v", sim.Misuses(), reported)
+	}
+	if maxRunning != 1 {
+		t.Errorf("expected at most one holder, got %d", maxRunning)
+	}
+}
+
+func TestAcquisitionReleasedAfterReleaseIsReported(t *testing.T) {
+	sim := NewSimulation(WithMisusePolicy(MisuseReport))
+	sem := NewCountingSemaphore(sim, 2)
+
+	acq := sem.Acquire(func(*Simulation) {})
+	sim.RunUntilDone()
+	sem.Release()
+	acq.Release()
+
+	if sem.executing != 0 {
+		t.Errorf("expected no units held, got %d", sem.executing)
+	}
+	if sim.Misuses() != 1 {
+		t.Errorf("expected the second release to be reported, got %d misuses", sim.Misuses())
+	}
+}
+
+func TestMisuseIsIgnoredByDefault(t *testing.T) {
+	sim := NewSimulation()
+	sem := NewCountingSemaphore(sim, 1)
+	sem.Release()
+	cancelled := sem.Acquire(func(*Simulation) {})
+	cancelled.Cancel()
+	cancelled.Release()
+
+	if sem.executing != 0 || sim.Misuses() != 0 {
+		t.Errorf("expected the misuse to have no effect, got %d units held and %d misuses", sem.executing, sim.Misuses())
+	}
+}
+
+func TestLeakReport(t *testing.T) {
+	sim := NewSimulation(WithLeakTracking())
+	sem := NewCountingSemaphore(sim, 3)
+	NewCondition(sim)
+
+	first := sem.AcquireN(2, func(*Simulation) {})
+	sem.Acquire(func(*Simulation) {})
+	waiting := sem.AcquireN(2, func(*Simulation) {})
+	sim.RunUntilDone()
+
+	report := sim.LeakReport()
+	if len(report.Held) != 2 || report.Held[0].Acquisition != first || report.Held[0].Units != 2 {
+		t.Errorf("expected two acquisitions to be held, got %+v", report.Held)
+	}
+	if len(report.Blocked) != 1 || report.Blocked[0].Condition != sem.readyToExecute || report.Blocked[0].Waiting != 1 {
+		t.Errorf("expected one action waiting for the semaphore, got %+v", report.Blocked)
+	}
+
+	// Releasing without the acquisition takes the units from the one that has held them the longest.
+	sem.ReleaseN(2)
+	sim.RunUntilDone()
+	report = sim.LeakReport()
+	if len(report.Held) != 2 || report.Held[1].Acquisition != waiting || len(report.Blocked) != 0 {
+		t.Errorf("expected the waiting acquisition to be held, got %+v", report)
+	}
+
+	sem.ReleaseN(3)
+	if report := sim.LeakReport(); !report.Empty() || report.String() != "no leaks" {
+		t.Errorf("expected no leaks, got %s", report)
+	}
+}
+
+func TestTryAcquireIsTracked(t *testing.T) {
+	sim := NewSimulation(WithLeakTracking(), WithMisusePolicy(MisuseReport))
+	sem := NewCountingSemaphore(sim, 3)
+
+	first := sem.TryAcquire()
+	second := sem.TryAcquireN(2)
+	if report := sim.LeakReport(); len(report.Held) != 2 || report.Held[0].Acquisition != first || report.Held[1].Units != 2 {
+		t.Errorf("expected both acquisitions to be held, got %+v", report.Held)
+	}
+
+	// Releasing the second acquisition releases its own units, not those of the first.
+	second.Release()
+	second.Release()
+	if sim.Misuses() != 1 {
+		t.Errorf("expected releasing twice to be reported once, got %d", sim.Misuses())
+	}
+	if report := sim.LeakReport(); len(report.Held) != 1 || report.Held[0].Acquisition != first {
+		t.Errorf("expected the first acquisition to still be held, got %+v", report.Held)
+	}
+
+	first.Release()
+	if report := sim.LeakReport(); !report.Empty() {
+		t.Errorf("expected no leaks, got %s", report)
+	}
+}
+
+func TestLeakReportWithoutTracking(t *testing.T) {
+	sim := NewSimulation()
+	sem := NewBinarySemaphore(sim)
+	sem.Acquire(func(*Simulation) {})
+	sem.Acquire(func(*Simulation) {})
+	sim.RunUntilDone()
+
+	if report := sim.LeakReport(); !report.Empty() {
+		t.Errorf("expected an empty report, got %s", report)
+	}
+}
@@ -0,0 +1,92 @@
+package steps
+
+import "time"
+
+// Option configures a Simulation. See NewSimulation.
+type Option func(*Simulation)
+
+// WithQueue makes the simulation use a specific Queue implementation to keep track of future events. The default is NewHeapQueue.
+func WithQueue(q Queue) Option {
+	return func(s *Simulation) {
+		s.queue = q
+	}
+}
+
+// WithStartTime sets the point in time the simulation starts at. The default is the zero time.Time.
+func WithStartTime(t time.Time) Option {
+	return func(s *Simulation) {
+		s.Now = t
+	}
+}
+
+// WithSeed sets the master random seed of the simulation, used to seed Simulation.Rand and every generator returned by Simulation.NewRand. Running the same model twice with the same seed gives the same result. The default seed is zero.
+func WithSeed(seed uint64) Option {
+	return func(s *Simulation) {
+		s.seed = seed
+	}
+}
+
+// WithHorizon sets the end time of the simulation. Events scheduled after the horizon are never processed, which makes it safe to use Simulation.RunUntilDone with models that never run out of events, such as those using a Ticker.
+func WithHorizon(t time.Time) Option {
+	return func(s *Simulation) {
+		s.horizon = t
+		s.hasHorizon = true
+	}
+}
+
+// WithEventBudget limits the total number of events the simulation processes to n. Once the budget is exhausted, runs return StopBudgetExhausted. This is useful to protect against runaway models, such as ones that keep scheduling events at the same time.
+func WithEventBudget(n int) Option {
+	return func(s *Simulation) {
+		s.budget = n
+		s.hasBudget = true
+	}
+}
+
+// WithPastPolicy decides what happens when an event is scheduled before the current time of the simulation. The default is PastAllow.
+func WithPastPolicy(p PastPolicy) Option {
+	return func(s *Simulation) {
+		s.pastPolicy = p
+	}
+}
+
+// WithMisusePolicy decides what happens when a resource is misused, for example when a semaphore is released more times than it has been acquired, or an Acquisition is released twice. The default is MisuseIgnore.
+func WithMisusePolicy(p MisusePolicy) Option {
+	return func(s *Simulation) {
+		s.misusePolicy = p
+	}
+}
+
+// WithLeakTracking makes the simulation keep track of acquisitions holding units of a resource, and of conditions that actions are waiting for, so that they can be listed using Simulation.LeakReport. This costs some memory and time for every condition and acquisition, and is meant to be used while developing a model.
+func WithLeakTracking() Option {
+	return func(s *Simulation) {
+		s.trackLeaks = true
+	}
+}
+
+// Hooks are callbacks that are called when things happen in a simulation. They are useful for logging, tracing, and collecting statistics. All hooks are optional.
+type Hooks struct {
+	// OnSchedule is called when an event has been scheduled.
+	OnSchedule func(s *Simulation, e ScheduledEvent)
+
+	// OnCancel is called when a scheduled event has been cancelled.
+	OnCancel func(s *Simulation, id EventID)
+
+	// BeforeEvent is called right before an event is processed. The simulation clock has already been advanced to the time of the event.
+	BeforeEvent func(s *Simulation, e ScheduledEvent)
+
+	// AfterEvent is called right after an event has been processed.
+	AfterEvent func(s *Simulation, e ScheduledEvent)
+
+	// OnPastEvent is called when an event is scheduled before the current time, if the PastPolicy of the simulation is PastWarn.
+	OnPastEvent func(s *Simulation, e Event)
+
+	// OnMisuse is called when a resource is misused, if the MisusePolicy of the simulation is MisuseReport. err wraps ErrResourceMisuse.
+	OnMisuse func(s *Simulation, err error)
+}
+
+// WithHooks registers hooks that observe the simulation. It can be given multiple times, in which case all hooks are called in the order they were given.
+func WithHooks(h Hooks) Option {
+	return func(s *Simulation) {
+		s.hooks = append(s.hooks, h)
+	}
+}
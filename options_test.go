@@ -0,0 +1,114 @@
+package steps
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// ExampleNewSimulation shows how to configure a simulation using options.
+func ExampleNewSimulation() {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	sim := NewSimulation(
+		WithStartTime(start),
+		WithHorizon(start.Add(3*time.Second)),
+		WithHooks(Hooks{
+			BeforeEvent: func(s *Simulation, e ScheduledEvent) {
+				fmt.Println("Processing event at", s.Now)
+			},
+		}),
+	)
+
+	Ticker(sim, sim.Now, time.Second, func(s *Simulation) {})
+
+	// The horizon makes sure this terminates, even though the Ticker never stops.
+	sim.RunUntilDone()
+
+	// Output:
+	// Processing event at 2025-01-01 00:00:00 +0000 UTC
+	// Processing event at 2025-01-01 00:00:01 +0000 UTC
+	// Processing event at 2025-01-01 00:00:02 +0000 UTC
+	// Processing event at 2025-01-01 00:00:03 +0000 UTC
+}
+
+func TestWithStartTime(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	sim := NewSimulation(WithStartTime(start))
+
+	if !sim.Now.Equal(start) {
+		t.Errorf("expected simulation to start at %s, got %s", start, sim.Now)
+	}
+}
+
+func TestWithSeed(t *testing.T) {
+	draw := func(seed uint64) (uint64, uint64) {
+		sim := NewSimulation(WithSeed(seed))
+		return sim.Rand.Uint64(), sim.NewRand().Uint64()
+	}
+
+	a1, b1 := draw(42)
+	a2, b2 := draw(42)
+	if a1 != a2 || b1 != b2 {
+		t.Error("expected the same seed to give the same random numbers")
+	}
+	if a1 == b1 {
+		t.Error("expected Rand and NewRand to give different streams")
+	}
+	if a3, _ := draw(43); a1 == a3 {
+		t.Error("expected different seeds to give different random numbers")
+	}
+}
+
+func TestNewRandGivesIndependentStreams(t *testing.T) {
+	sim := NewSimulation()
+	r1 := sim.NewRand()
+	r2 := sim.NewRand()
+
+	if r1.Uint64() == r2.Uint64() {
+		t.Error("expected different streams")
+	}
+}
+
+func TestWithHorizon(t *testing.T) {
+	sim := NewSimulation(WithHorizon(time.Time{}.Add(10 * time.Second)))
+
+	var timesCalled []time.Time
+	Ticker(sim, sim.Now, 3*time.Second, func(s *Simulation) {
+		timesCalled = append(timesCalled, s.Now)
+	})
+	sim.RunUntilDone()
+
+	if expectedTimesCalled := 4; len(timesCalled) != expectedTimesCalled {
+		t.Errorf("expected %d times called, got %d", expectedTimesCalled, len(timesCalled))
+	}
+	if sim.Step() {
+		t.Error("expected no step to be taken after the horizon")
+	}
+}
+
+func TestWithHooks(t *testing.T) {
+	var calls []string
+	hooks := Hooks{
+		OnSchedule:  func(s *Simulation, e ScheduledEvent) { calls = append(calls, "schedule") },
+		OnCancel:    func(s *Simulation, id EventID) { calls = append(calls, "cancel") },
+		BeforeEvent: func(s *Simulation, e ScheduledEvent) { calls = append(calls, "before") },
+		AfterEvent:  func(s *Simulation, e ScheduledEvent) { calls = append(calls, "after") },
+	}
+	sim := NewSimulation(WithHooks(hooks), WithHooks(Hooks{
+		BeforeEvent: func(s *Simulation, e ScheduledEvent) { calls = append(calls, "before2") },
+	}))
+
+	id := sim.Schedule(Event{When: sim.Now.Add(time.Second), Action: func(s *Simulation) {
+		calls = append(calls, "action")
+	}})
+	sim.Cancel(id)
+	sim.Schedule(Event{When: sim.Now.Add(time.Second), Action: func(s *Simulation) {
+		calls = append(calls, "action")
+	}})
+	sim.RunUntilDone()
+
+	expected := []string{"schedule", "cancel", "schedule", "before", "before2", "action", "after"}
+	if fmt.Sprint(calls) != fmt.Sprint(expected) {
+		t.Errorf("expected calls %v, got %v", expected, calls)
+	}
+}
@@ -0,0 +1,171 @@
+package steps
+
+import "fmt"
+
+// NewPairingHeap creates a pairing heap[1] based Queue. A pairing heap has O(1) push and amortized O(log n) pop, and is often faster than a binary heap when many events are pushed compared to how many are popped, or when many events are cancelled.
+//
+// [1]: https://en.wikipedia.org/wiki/Pairing_heap
+func NewPairingHeap() Queue {
+	return &pairingHeap{
+		nodes: make(map[EventID]*pairingNode),
+	}
+}
+
+// pairingHeap is a pairing heap of events.
+type pairingHeap struct {
+	root  *pairingNode
+	nodes map[EventID]*pairingNode
+
+	// free is a list of nodes, linked through sibling, that can be reused to avoid allocations.
+	free *pairingNode
+}
+
+// pairingNode is a node in a pairing heap.
+type pairingNode struct {
+	event ScheduledEvent
+
+	// child is the leftmost child of the node.
+	child *pairingNode
+
+	// sibling is the next sibling to the right of the node.
+	sibling *pairingNode
+
+	// prev is the sibling to the left of the node, or its parent if it is the leftmost child.
+	prev *pairingNode
+}
+
+// Push adds an event to the queue.
+func (h *pairingHeap) Push(e ScheduledEvent) {
+	if _, found := h.nodes[e.ID]; found {
+		panic(fmt.Sprintf("event with ID %d already exists", e.ID))
+	}
+	n := h.free
+	if n != nil {
+		h.free = n.sibling
+		n.sibling = nil
+	} else {
+		n = &pairingNode{}
+	}
+	n.event = e
+	h.nodes[e.ID] = n
+	h.root = meldPairingNodes(h.root, n)
+}
+
+// Pop removes the next event from the queue.
+func (h *pairingHeap) Pop() ScheduledEvent {
+	n := h.root
+	h.root = mergePairingNodes(n.child)
+	return h.release(n)
+}
+
+// Peek returns the next event in the queue without removing it.
+func (h *pairingHeap) Peek() ScheduledEvent {
+	return h.root.event
+}
+
+// Remove removes an event from the queue. Returns true if the event was found and removed, false otherwise.
+func (h *pairingHeap) Remove(id EventID) bool {
+	n, found := h.nodes[id]
+	if !found {
+		return false
+	}
+	if n == h.root {
+		h.Pop()
+		return true
+	}
+
+	// Detach the subtree rooted at n and meld its children back into the heap.
+	if n.prev.child == n {
+		n.prev.child = n.sibling
+	} else {
+		n.prev.sibling = n.sibling
+	}
+	if n.sibling != nil {
+		n.sibling.prev = n.prev
+	}
+	h.root = meldPairingNodes(h.root, mergePairingNodes(n.child))
+	h.release(n)
+	return true
+}
+
+// Len returns the number of events in the queue.
+func (h *pairingHeap) Len() int {
+	return len(h.nodes)
+}
+
+// Lookup returns the event with the given ID.
+func (h *pairingHeap) Lookup(id EventID) (ScheduledEvent, bool) {
+	n, found := h.nodes[id]
+	if !found {
+		return ScheduledEvent{}, false
+	}
+	return n.event, true
+}
+
+// Update replaces the event that has the same ID as e with e.
+func (h *pairingHeap) Update(e ScheduledEvent) bool {
+	if !h.Remove(e.ID) {
+		return false
+	}
+	h.Push(e)
+	return true
+}
+
+// release puts a node that has been taken out of the heap on the free list and returns its event.
+func (h *pairingHeap) release(n *pairingNode) ScheduledEvent {
+	e := n.event
+	delete(h.nodes, e.ID)
+	*n = pairingNode{sibling: h.free}
+	h.free = n
+	return e
+}
+
+// meldPairingNodes melds two heaps, returning the new root. Both roots must be detached, that is, have no siblings or parent.
+func meldPairingNodes(a, b *pairingNode) *pairingNode {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if b.event.Before(a.event) {
+		a, b = b, a
+	}
+	b.prev = a
+	b.sibling = a.child
+	if a.child != nil {
+		a.child.prev = b
+	}
+	a.child = b
+	return a
+}
+
+// mergePairingNodes melds a list of siblings into a single heap using the standard two-pass strategy, returning the new, detached, root.
+func mergePairingNodes(first *pairingNode) *pairingNode {
+	// First pass: meld pairs from left to right, collecting the results in reverse order.
+	var pairs *pairingNode
+	for first != nil {
+		a := first
+		b := a.sibling
+		first = nil
+		if b != nil {
+			first = b.sibling
+			b.sibling, b.prev = nil, nil
+		}
+		a.sibling, a.prev = nil, nil
+
+		melded := meldPairingNodes(a, b)
+		melded.sibling = pairs
+		pairs = melded
+	}
+
+	// Second pass: meld the pairs from right to left.
+	var root *pairingNode
+	for pairs != nil {
+		next := pairs.sibling
+		pairs.sibling = nil
+		root = meldPairingNodes(root, pairs)
+		pairs = next
+	}
+	return root
+}
@@ -0,0 +1,141 @@
+package steps
+
+import (
+	"slices"
+	"time"
+)
+
+// PriorityResource is a resource with a limited number of units, like a CountingSemaphore, where actions waiting for a unit are served by priority instead of in the order they started waiting. Actions with a lower priority are served first, and actions with the same priority are served in the order they started waiting.
+type PriorityResource struct {
+	semaphore *CountingSemaphore
+}
+
+// NewPriorityResource creates a new priority resource with count units.
+func NewPriorityResource(sim *Simulation, count int, opts ...ResourceOption) *PriorityResource {
+	return &PriorityResource{
+		semaphore: newCountingSemaphore(sim, count, newResourceOptions("PriorityResource", opts, "WithFairness")),
+	}
+}
+
+// Acquire acquires a unit of the resource with priority zero. See AcquireWithPriority.
+func (r *PriorityResource) Acquire(a Action) *Acquisition {
+	return r.AcquireWithPriority(0, a)
+}
+
+// AcquireWithPriority acquires a unit of the resource. If no unit is available, the action will be scheduled to run when a unit is released and there are no actions with a lower priority waiting. The action is scheduled with the priority (see Event.Priority). Do not forget to call Release() when the action is done.
+//
+// The returned Acquisition can be used to stop waiting for the resource.
+func (r *PriorityResource) AcquireWithPriority(priority int, a Action) *Acquisition {
+	return r.semaphore.acquire(1, priority, a)
+}
+
+// Release releases a unit of the resource, handing it over to the waiting action with the lowest priority, if any.
+func (r *PriorityResource) Release() {
+	r.semaphore.Release()
+}
+
+// Stats returns statistics about how the resource has been used. See CountingSemaphore.Stats.
+func (r *PriorityResource) Stats() ResourceStats {
+	return r.semaphore.Stats()
+}
+
+// Preemption describes how a holder of a PreemptiveResource was preempted.
+type Preemption struct {
+	// By is the priority of the acquisition that preempted the holder.
+	By int
+
+	// Since is when the holder was handed the resource, and HeldFor is how long it held it before being preempted.
+	Since   time.Time
+	HeldFor time.Duration
+}
+
+// PreemptiveResource is like a PriorityResource, but an acquisition that would have to wait evicts a current holder with a higher priority value (that is, a less important one) instead. The evicted holder is the least important one, and among those the one that acquired the resource last. Preemption only happens when acquiring; an acquisition that is already waiting doesn't evict holders later on.
+//
+// The evicted holder is notified with a Preemption, which the model can use to resume or requeue whatever was interrupted. Since holders can be evicted, units are released by giving back their Acquisition, see Release and Acquisition.Release.
+type PreemptiveResource struct {
+	sim       *Simulation
+	semaphore *CountingSemaphore
+
+	// holders holds the acquisitions that have been handed a unit, in the order they got it.
+	holders []*Acquisition
+}
+
+// NewPreemptiveResource creates a new preemptive resource with count units.
+func NewPreemptiveResource(sim *Simulation, count int, opts ...ResourceOption) *PreemptiveResource {
+	r := &PreemptiveResource{
+		sim:       sim,
+		semaphore: newCountingSemaphore(sim, count, newResourceOptions("PreemptiveResource", opts, "WithFairness")),
+	}
+	r.semaphore.onGranted = func(acq *Acquisition) {
+		r.holders = append(r.holders, acq)
+	}
+	return r
+}
+
+// Acquire acquires a unit of the resource. If no unit is available, the least important holder with a higher priority value than priority is evicted, and its onPreempted is scheduled to run. If there is no such holder, the acquisition waits like for a PriorityResource. Lower priority values are more important.
+//
+// If the acquisition is itself preempted later on, onPreempted is run and the unit must not be released. If it is preempted before a has run, a is never run. Otherwise, the returned Acquisition must be given to Release once done with the resource. It can also be used to stop waiting for the resource.
+func (r *PreemptiveResource) Acquire(priority int, a Action, onPreempted func(*Simulation, Preemption)) *Acquisition {
+	acq := r.semaphore.acquire(1, priority, a)
+	acq.onPreempted = onPreempted
+	if acq.queued {
+		if victim := r.victim(priority); victim >= 0 {
+			r.evict(victim, priority)
+		}
+	}
+	return acq
+}
+
+// Release releases the unit held by acq, handing it over to the waiting action with the lowest priority, if any. Returns false if acq doesn't hold a unit, for example because it was preempted, in which case nothing is released.
+func (r *PreemptiveResource) Release(acq *Acquisition) bool {
+	if acq.held == 0 {
+		return false
+	}
+	r.semaphore.release(acq)
+	r.prune()
+	return true
+}
+
+// prune forgets holders that no longer hold a unit, for example because they were released using Acquisition.Release.
+func (r *PreemptiveResource) prune() {
+	r.holders = slices.DeleteFunc(r.holders, func(acq *Acquisition) bool {
+		return acq.held == 0
+	})
+}
+
+// Stats returns statistics about how the resource has been used. See CountingSemaphore.Stats.
+func (r *PreemptiveResource) Stats() ResourceStats {
+	return r.semaphore.Stats()
+}
+
+// victim returns the index of the holder that an acquisition with the given priority should evict, or -1 if there is none.
+func (r *PreemptiveResource) victim(priority int) int {
+	r.prune()
+	victim := -1
+	for i, h := range r.holders {
+		if h.priority > priority && (victim < 0 || h.priority >= r.holders[victim].priority) {
+			victim = i
+		}
+	}
+	return victim
+}
+
+// evict takes the unit back from the holder at index i and hands it over to the next waiting action.
+func (r *PreemptiveResource) evict(i int, by int) {
+	acq := r.holders[i]
+	r.holders = slices.Delete(r.holders, i, i+1)
+
+	acq.revoked = true
+	if acq.event != 0 {
+		// The holder's action hasn't run yet and never will.
+		r.sim.Cancel(acq.event)
+		acq.event = 0
+	}
+	p := Preemption{By: by, Since: acq.since, HeldFor: r.sim.Now.Sub(acq.since)}
+	if acq.onPreempted != nil {
+		r.sim.Immediately(func(sim *Simulation) {
+			acq.onPreempted(sim, p)
+		})
+	}
+	r.semaphore.release(acq)
+}
@@ -0,0 +1,122 @@
+package steps
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// ExamplePriorityResource simulates a single doctor, serving patients by urgency rather than by arrival.
+func ExamplePriorityResource() {
+	sim := NewSimulation()
+	doctor := NewPriorityResource(sim, 1)
+
+	patients := []struct {
+		name    string
+		urgency int
+	}{{"Alice", 2}, {"Bob", 3}, {"Carol", 1}, {"Dave", 2}}
+	for _, patient := range patients {
+		doctor.AcquireWithPriority(patient.urgency, func(sim *Simulation) {
+			fmt.Println(sim.Now, "Treating", patient.name)
+			sim.After(10*time.Minute, func(*Simulation) { doctor.Release() })
+		})
+	}
+	sim.RunUntilDone()
+
+	// Output:
+	// 0001-01-01 00:00:00 +0000 UTC Treating Alice
+	// 0001-01-01 00:10:00 +0000 UTC Treating Carol
+	// 0001-01-01 00:20:00 +0000 UTC Treating Dave
+	// 0001-01-01 00:30:00 +0000 UTC Treating Bob
+}
+
+// ExamplePreemptiveResource simulates a machine where urgent jobs preempt regular ones. A preempted job is requeued with the work it has left.
+func ExamplePreemptiveResource() {
+	sim := NewSimulation()
+	machine := NewPreemptiveResource(sim, 1)
+
+	var run func(name string, priority int, work time.Duration)
+	run = func(name string, priority int, work time.Duration) {
+		var acq *Acquisition
+		var done EventID
+		acq = machine.Acquire(priority, func(sim *Simulation) {
+			fmt.Println(sim.Now, "Running", name)
+			done = sim.After(work, func(sim *Simulation) {
+				fmt.Println(sim.Now, "Finished", name)
+				machine.Release(acq)
+			})
+		}, func(sim *Simulation, p Preemption) {
+			sim.Cancel(done)
+			fmt.Println(sim.Now, "Preempted", name, "after", p.HeldFor)
+			run(name, priority, work-p.HeldFor)
+		})
+	}
+	run("regular", 1, time.Hour)
+	sim.After(20*time.Minute, func(*Simulation) { run("urgent", 0, 10*time.Minute) })
+	sim.RunUntilDone()
+
+	// Output:
+	// 0001-01-01 00:00:00 +0000 UTC Running regular
+	// 0001-01-01 00:20:00 +0000 UTC Preempted regular after 20m0s
+	// 0001-01-01 00:20:00 +0000 UTC Running urgent
+	// 0001-01-01 00:30:00 +0000 UTC Finished urgent
+	// 0001-01-01 00:30:00 +0000 UTC Running regular
+	// 0001-01-01 01:10:00 +0000 UTC Finished regular
+}
+
+func TestPreemptiveResourceEvictsLeastImportantLatestHolder(t *testing.T) {
+	sim := NewSimulation()
+	r := NewPreemptiveResource(sim, 3)
+
+	var preempted []string
+	acquire := func(name string, priority int) *Acquisition {
+		return r.Acquire(priority, func(*Simulation) {}, func(*Simulation, Preemption) {
+			preempted = append(preempted, name)
+		})
+	}
+	acquire("a", 2)
+	b := acquire("b", 3)
+	acquire("c", 3)
+	sim.RunUntilDone()
+
+	// Equally important acquisitions wait instead of preempting.
+	equal := acquire("equal", 3)
+	sim.RunUntilDone()
+	if len(preempted) != 0 || !equal.queued {
+		t.Errorf("expected no preemption, got %v", preempted)
+	}
+
+	acquire("urgent", 1)
+	acquire("urgent", 1)
+	sim.RunUntilDone()
+	if expected := []string{"c", "b"}; fmt.Sprint(preempted) != fmt.Sprint(expected) {
+		t.Errorf("expected %v to be preempted, got %v", expected, preempted)
+	}
+	if r.Release(b) {
+		t.Error("expected releasing a preempted acquisition to return false")
+	}
+}
+
+func TestPreemptiveResourceEvictsBeforeActionRuns(t *testing.T) {
+	sim := NewSimulation()
+	r := NewPreemptiveResource(sim, 1)
+
+	ran := false
+	var preemption Preemption
+	r.Acquire(1, func(*Simulation) { ran = true }, func(_ *Simulation, p Preemption) { preemption = p })
+	urgent := r.Acquire(0, func(*Simulation) {}, nil)
+	sim.RunUntilDone()
+
+	if ran {
+		t.Error("expected the action of the preempted acquisition to never run")
+	}
+	if preemption.By != 0 || preemption.HeldFor != 0 {
+		t.Errorf("expected to be preempted by priority 0 right away, got %+v", preemption)
+	}
+	if !r.Release(urgent) {
+		t.Error("expected the urgent acquisition to hold the resource")
+	}
+	if r.semaphore.executing != 0 {
+		t.Errorf("expected no units to be in use, got %d", r.semaphore.executing)
+	}
+}
@@ -0,0 +1,287 @@
+package steps
+
+import (
+	"iter"
+	"time"
+)
+
+// ProcessFunc is the body of a process. It yields commands, such as Timeout, Acquire or Wait, to the simulation and is resumed once each command has completed, or the process has been interrupted (see Process.Interrupted). If yield returns false, the process has been killed and the body must return.
+//
+// Since the process is passed in, the body can be a plain function instead of a closure over the simulation.
+type ProcessFunc func(p *Process, yield func(Command) bool)
+
+// Command is something a process waits for. See Timeout, Acquire, Wait, Join, Await and Select.
+type Command interface {
+	// start makes the simulation resume p once the command has completed.
+	start(p *Process)
+
+	// cancel stops the command from resuming p. Returns false if the command has already completed and p is about to be resumed.
+	cancel(p *Process) bool
+}
+
+// Process is a long-lived entity in a simulation, whose behaviour is written as a sequential function instead of a chain of Actions. This avoids deeply nested callbacks for entities that go through many steps.
+//
+// Processes are built on coroutines (see iter.Pull). The body of a process runs only while the simulation resumes it, and the simulation waits for the body to yield before continuing. This means that processes don't run in parallel and that a simulation using processes is just as deterministic as one using Actions. Note that a process that never returns keeps its coroutine alive, unless it is killed.
+type Process struct {
+	sim *Simulation
+
+	// next resumes the body until it yields the next command. stop makes yield return false.
+	next func() (Command, bool)
+	stop func()
+
+	// resume is the Action that resumes the process. It is stored to avoid allocating a new method value every time the process waits.
+	resume Action
+
+	// current is the command the process is waiting for, or nil if it isn't waiting for anything. event, waitID and acquisition are used by commands to keep track of what to cancel.
+	current     Command
+	event       EventID
+	waitID      ConditionActionID
+	acquisition *Acquisition
+
+	// generation is incremented every time the process is resumed or stops waiting for a command. It is used by commands that cannot be cancelled to detect that they are stale.
+	generation uint64
+
+	// interrupts holds the causes of interrupts that haven't been delivered yet. cause and interrupted hold the interrupt delivered at the current yield point, if any.
+	interrupts  []any
+	cause       any
+	interrupted bool
+
+	started bool
+	running bool
+	done    bool
+
+	// finished is signaled when the process is done. It is created lazily by doneCondition.
+	finished *Condition
+}
+
+// NewProcess creates a process running body. The process is started as soon as possible, within the simulation loop.
+func NewProcess(sim *Simulation, body ProcessFunc) *Process {
+	p := &Process{sim: sim}
+	p.next, p.stop = iter.Pull(func(yield func(Command) bool) {
+		body(p, yield)
+	})
+	p.resume = p.step
+	sim.Immediately(p.resume)
+	return p
+}
+
+// Sim returns the simulation the process is running in.
+func (p *Process) Sim() *Simulation {
+	return p.sim
+}
+
+// Done returns true if the body of the process has returned, or the process has been killed.
+func (p *Process) Done() bool {
+	return p.done
+}
+
+// Interrupt interrupts the process. If it is waiting for a command, the command is cancelled and the process is resumed as soon as possible, with Interrupted returning cause. Otherwise, for example if the command has already completed but the process is yet to be resumed, the interrupt is delivered at the next yield point instead. Multiple interrupts are delivered one at a time, in order. Returns false if the process is done.
+//
+// Note that an interrupted process no longer holds a place in line for whatever it was waiting for. For example, a process interrupted while waiting to acquire a resource has to yield Acquire again.
+func (p *Process) Interrupt(cause any) bool {
+	if p.done {
+		return false
+	}
+	p.interrupts = append(p.interrupts, cause)
+	if p.current != nil && p.current.cancel(p) {
+		p.current = nil
+		p.generation++
+		p.sim.Immediately(p.resume)
+	}
+	return true
+}
+
+// Interrupted returns the cause of the interrupt that resumed the process at its current yield point, if any. It is meant to be called by the body of the process right after yield returns.
+func (p *Process) Interrupted() (cause any, ok bool) {
+	return p.cause, p.interrupted
+}
+
+// Kill stops the process. Whatever the process is waiting for is cancelled, and yield returns false in its body, which must then return. Returns false if the process is already done. A process cannot kill itself; it should return from its body instead.
+func (p *Process) Kill() bool {
+	if p.done {
+		return false
+	}
+	if p.running {
+		panic("a process cannot kill itself, return from its body instead")
+	}
+	if p.current != nil {
+		// If the command has already completed, the pending resume is ignored since the process is done.
+		p.current.cancel(p)
+		p.current = nil
+		p.generation++
+	}
+	p.stop()
+	p.finish()
+	return true
+}
+
+// Join makes a wait until the process is done. If the process is already done, a is run as soon as possible.
+func (p *Process) Join(a Action) {
+	if p.done {
+		p.sim.Immediately(a)
+		return
+	}
+	p.doneCondition().Wait(a)
+}
+
+// doneCondition returns the condition that is broadcast once the process is done.
+func (p *Process) doneCondition() *Condition {
+	if p.finished == nil {
+		p.finished = NewCondition(p.sim)
+	}
+	return p.finished
+}
+
+// step resumes the body of the process, and starts the command it yields.
+func (p *Process) step(*Simulation) {
+	if p.done {
+		// The process was killed while it was about to be resumed.
+		return
+	}
+
+	p.current = nil
+	p.event = 0
+	p.acquisition = nil
+	p.generation++
+	p.cause, p.interrupted = nil, false
+	if p.started && len(p.interrupts) > 0 {
+		p.cause, p.interrupted = p.interrupts[0], true
+		p.interrupts = p.interrupts[1:]
+	}
+	p.started = true
+
+	p.running = true
+	cmd, ok := p.next()
+	p.running = false
+	if !ok {
+		p.finish()
+		return
+	}
+	if len(p.interrupts) > 0 {
+		// The process was interrupted while it was running, or before it started. Deliver the interrupt right away instead of starting the command.
+		p.sim.Immediately(p.resume)
+		return
+	}
+	p.current = cmd
+	cmd.start(p)
+}
+
+// finish marks the process as done and wakes up everyone joining it.
+func (p *Process) finish() {
+	p.done = true
+	p.interrupts = nil
+	if p.finished != nil {
+		p.finished.Broadcast()
+	}
+}
+
+// Timeout returns a command that makes a process wait for the duration d.
+func Timeout(d time.Duration) Command {
+	return timeoutCommand{d: d}
+}
+
+type timeoutCommand struct {
+	d time.Duration
+}
+
+func (c timeoutCommand) start(p *Process) {
+	p.event = p.sim.After(c.d, p.resume)
+}
+
+func (c timeoutCommand) cancel(p *Process) bool {
+	return p.sim.Cancel(p.event)
+}
+
+// Acquirer is a resource that a process can acquire, such as a CountingSemaphore or a BinarySemaphore.
+type Acquirer interface {
+	Acquire(a Action) *Acquisition
+	Release()
+}
+
+// Acquire returns a command that makes a process wait until it has acquired r. Do not forget to release r once done with it.
+func Acquire(r Acquirer) Command {
+	return acquireCommand{r: r}
+}
+
+type acquireCommand struct {
+	r Acquirer
+}
+
+func (c acquireCommand) start(p *Process) {
+	generation := p.generation
+	var acq *Acquisition
+	acq = c.r.Acquire(func(sim *Simulation) {
+		if p.generation != generation || p.done {
+			// The process was interrupted or killed after the resource had been handed over to it. Pass the resource on to the next in line.
+			acq.Release()
+			return
+		}
+		p.step(sim)
+	})
+	p.acquisition = acq
+}
+
+func (c acquireCommand) cancel(p *Process) bool {
+	// If the resource has already been handed over, the acquisition cannot be cancelled. Instead, it is released as soon as it is granted, which is detected since the process has been resumed in between.
+	p.acquisition.Cancel()
+	return true
+}
+
+// Wait returns a command that makes a process wait until c is signaled.
+func Wait(c *Condition) Command {
+	return waitCommand{c: c}
+}
+
+type waitCommand struct {
+	c *Condition
+}
+
+func (c waitCommand) start(p *Process) {
+	p.waitID = c.c.Wait(p.resume)
+}
+
+func (c waitCommand) cancel(p *Process) bool {
+	return c.c.Cancel(p.waitID)
+}
+
+// WaitUntil returns a command that makes a process wait until c is signaled while pred returns true. See Condition.WaitUntil.
+func WaitUntil(c *Condition, pred func() bool) Command {
+	return waitUntilCommand{c: c, pred: pred}
+}
+
+type waitUntilCommand struct {
+	c    *Condition
+	pred func() bool
+}
+
+func (c waitUntilCommand) start(p *Process) {
+	p.waitID = c.c.WaitUntil(c.pred, p.resume)
+}
+
+func (c waitUntilCommand) cancel(p *Process) bool {
+	return c.c.Cancel(p.waitID)
+}
+
+// Join returns a command that makes a process wait until other is done.
+func Join(other *Process) Command {
+	return joinCommand{other: other}
+}
+
+type joinCommand struct {
+	other *Process
+}
+
+func (c joinCommand) start(p *Process) {
+	if c.other.done {
+		p.event = p.sim.Immediately(p.resume)
+		return
+	}
+	p.waitID = c.other.doneCondition().Wait(p.resume)
+}
+
+func (c joinCommand) cancel(p *Process) bool {
+	if p.event != 0 {
+		return p.sim.Cancel(p.event)
+	}
+	return c.other.finished.Cancel(p.waitID)
+}
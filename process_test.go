@@ -0,0 +1,434 @@
+package steps
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// ExampleProcess shows how to write a multi-step entity as a process instead of nested actions. It simulates processing five (5) items, two (2) at a time, like ExampleCountingSemaphore.
+func ExampleProcess() {
+	sim := NewSimulation()
+	sem := NewCountingSemaphore(sim, 2)
+
+	timeToProcess := 10 * time.Second
+	for i := range 5 {
+		NewProcess(sim, func(p *Process, yield func(Command) bool) {
+			if !yield(Acquire(sem)) {
+				return
+			}
+			fmt.Println(p.Sim().Now, "Processing item", i)
+
+			if !yield(Timeout(timeToProcess)) {
+				return
+			}
+			fmt.Println(p.Sim().Now, "Done processing item", i)
+			sem.Release()
+		})
+	}
+	sim.RunUntilDone()
+
+	// Output:
+	// 0001-01-01 00:00:00 +0000 UTC Processing item 0
+	// 0001-01-01 00:00:00 +0000 UTC Processing item 1
+	// 0001-01-01 00:00:10 +0000 UTC Done processing item 0
+	// 0001-01-01 00:00:10 +0000 UTC Processing item 2
+	// 0001-01-01 00:00:10 +0000 UTC Done processing item 1
+	// 0001-01-01 00:00:10 +0000 UTC Processing item 3
+	// 0001-01-01 00:00:20 +0000 UTC Done processing item 2
+	// 0001-01-01 00:00:20 +0000 UTC Processing item 4
+	// 0001-01-01 00:00:20 +0000 UTC Done processing item 3
+	// 0001-01-01 00:00:30 +0000 UTC Done processing item 4
+}
+
+func TestProcessTimeouts(t *testing.T) {
+	sim := NewSimulation()
+
+	var times []time.Time
+	p := NewProcess(sim, func(p *Process, yield func(Command) bool) {
+		for range 3 {
+			times = append(times, p.Sim().Now)
+			if !yield(Timeout(time.Second)) {
+				return
+			}
+		}
+	})
+	if p.Done() {
+		t.Error("expected process to not be done before the simulation runs")
+	}
+	sim.RunUntilDone()
+
+	if !p.Done() {
+		t.Error("expected process to be done")
+	}
+	var start time.Time
+	expected := []time.Time{start, start.Add(time.Second), start.Add(2 * time.Second)}
+	if fmt.Sprint(times) != fmt.Sprint(expected) {
+		t.Errorf("expected times %v, got %v", expected, times)
+	}
+	if !sim.Now.Equal(start.Add(3 * time.Second)) {
+		t.Errorf("expected simulation to end at %s, got %s", start.Add(3*time.Second), sim.Now)
+	}
+}
+
+func TestProcessWait(t *testing.T) {
+	sim := NewSimulation()
+	c := NewCondition(sim)
+
+	var wokenAt time.Time
+	p := NewProcess(sim, func(p *Process, yield func(Command) bool) {
+		if !yield(Wait(c)) {
+			return
+		}
+		wokenAt = p.Sim().Now
+	})
+	sim.After(5*time.Second, func(*Simulation) { c.Signal() })
+	sim.RunUntilDone()
+
+	if !p.Done() {
+		t.Error("expected process to be done")
+	}
+	if expected := (time.Time{}).Add(5 * time.Second); !wokenAt.Equal(expected) {
+		t.Errorf("expected process to be woken at %s, got %s", expected, wokenAt)
+	}
+}
+
+func TestProcessesAreDeterministic(t *testing.T) {
+	run := func() string {
+		sim := NewSimulation(WithSeed(42))
+		sem := NewBinarySemaphore(sim)
+
+		var log []string
+		for i := range 10 {
+			r := sim.NewRand()
+			NewProcess(sim, func(p *Process, yield func(Command) bool) {
+				for j := range 3 {
+					if !yield(Timeout(time.Duration(r.IntN(10)) * time.Second)) {
+						return
+					}
+					if !yield(Acquire(sem)) {
+						return
+					}
+					log = append(log, fmt.Sprint(p.Sim().Now, i, j))
+					sem.Release()
+				}
+			})
+		}
+		sim.RunUntilDone()
+		return fmt.Sprint(log)
+	}
+
+	expected := run()
+	for range 10 {
+		if got := run(); got != expected {
+			t.Fatalf("expected the same result every run, got %s and %s", expected, got)
+		}
+	}
+}
+
+// ExampleProcess_Interrupt shows how a machine breakdown can interrupt the job currently being serviced.
+func ExampleProcess_Interrupt() {
+	sim := NewSimulation()
+
+	job := NewProcess(sim, func(p *Process, yield func(Command) bool) {
+		remaining := 10 * time.Second
+		for remaining > 0 {
+			started := p.Sim().Now
+			if !yield(Timeout(remaining)) {
+				return
+			}
+			if cause, ok := p.Interrupted(); ok {
+				remaining -= p.Sim().Now.Sub(started)
+				fmt.Println(p.Sim().Now, "Interrupted by", cause, "with", remaining, "left")
+
+				// Wait for the repair before resuming.
+				if !yield(Timeout(5 * time.Second)) {
+					return
+				}
+				continue
+			}
+			remaining = 0
+		}
+		fmt.Println(p.Sim().Now, "Job done")
+	})
+	sim.After(3*time.Second, func(*Simulation) {
+		job.Interrupt("breakdown")
+	})
+	sim.RunUntilDone()
+
+	// Output:
+	// 0001-01-01 00:00:03 +0000 UTC Interrupted by breakdown with 7s left
+	// 0001-01-01 00:00:15 +0000 UTC Job done
+}
+
+func TestProcessInterruptWhileWaitingForCondition(t *testing.T) {
+	sim := NewSimulation()
+	c := NewCondition(sim)
+
+	var events []string
+	p := NewProcess(sim, func(p *Process, yield func(Command) bool) {
+		if !yield(Wait(c)) {
+			return
+		}
+		cause, ok := p.Interrupted()
+		events = append(events, fmt.Sprint("first: ", cause, " ", ok))
+		if !yield(Wait(c)) {
+			return
+		}
+		cause, ok = p.Interrupted()
+		events = append(events, fmt.Sprint("second: ", cause, " ", ok))
+	})
+	other := &testAction{}
+	sim.After(time.Second, func(*Simulation) {
+		p.Interrupt("stop waiting")
+		c.Wait(other.Execute)
+	})
+	sim.After(2*time.Second, func(*Simulation) {
+		// The interrupted wait has been cancelled, so this wakes up the other action and then the process.
+		c.Signal()
+		c.Signal()
+	})
+	sim.RunUntilDone()
+
+	if !other.executed {
+		t.Error("expected the other action to be woken up by the first signal")
+	}
+	expected := []string{"first: stop waiting true", "second: <nil> false"}
+	if fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+}
+
+func TestProcessInterruptWhileAcquiring(t *testing.T) {
+	sim := NewSimulation()
+	sem := NewBinarySemaphore(sim)
+
+	var events []string
+	holder := NewProcess(sim, func(p *Process, yield func(Command) bool) {
+		if !yield(Acquire(sem)) {
+			return
+		}
+		if !yield(Timeout(10 * time.Second)) {
+			return
+		}
+		sem.Release()
+	})
+	reneger := NewProcess(sim, func(p *Process, yield func(Command) bool) {
+		if !yield(Acquire(sem)) {
+			return
+		}
+		if _, ok := p.Interrupted(); ok {
+			events = append(events, fmt.Sprint(p.Sim().Now, " reneged"))
+			return
+		}
+		events = append(events, "reneger acquired")
+		sem.Release()
+	})
+	NewProcess(sim, func(p *Process, yield func(Command) bool) {
+		if !yield(Acquire(sem)) {
+			return
+		}
+		events = append(events, fmt.Sprint(p.Sim().Now, " patient acquired"))
+		sem.Release()
+	})
+	sim.After(5*time.Second, func(*Simulation) { reneger.Interrupt("tired of waiting") })
+	sim.RunUntilDone()
+
+	if !holder.Done() || !reneger.Done() {
+		t.Error("expected all processes to be done")
+	}
+	var start time.Time
+	expected := []string{fmt.Sprint(start.Add(5*time.Second), " reneged"), fmt.Sprint(start.Add(10*time.Second), " patient acquired")}
+	if fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+}
+
+func TestProcessInterruptedWhenAcquired(t *testing.T) {
+	sim := NewSimulation(WithMisusePolicy(MisuseReport))
+	sem := NewBinarySemaphore(sim)
+
+	holder := sem.TryAcquire()
+	waiter := NewProcess(sim, func(p *Process, yield func(Command) bool) {
+		if !yield(Acquire(sem)) {
+			return
+		}
+		if _, ok := p.Interrupted(); !ok {
+			t.Error("expected the process to be interrupted")
+			sem.Release()
+		}
+	})
+	sim.After(time.Second, func(*Simulation) {
+		// The semaphore is handed over to the waiter, which is interrupted before it has been resumed.
+		holder.Release()
+		waiter.Interrupt("too late")
+	})
+	sim.RunUntilDone()
+
+	if sem.semaphore.executing != 0 {
+		t.Errorf("expected the semaphore to be passed on, got %d units held", sem.semaphore.executing)
+	}
+	if sim.Misuses() != 0 {
+		t.Errorf("expected no misuse, got %d", sim.Misuses())
+	}
+}
+
+func TestProcessInterruptBeforeStart(t *testing.T) {
+	sim := NewSimulation()
+
+	var interruptedAt []string
+	p := NewProcess(sim, func(p *Process, yield func(Command) bool) {
+		if _, ok := p.Interrupted(); ok {
+			t.Error("expected the interrupt to be delivered at the first yield point")
+		}
+		if !yield(Timeout(time.Second)) {
+			return
+		}
+		if cause, ok := p.Interrupted(); ok {
+			interruptedAt = append(interruptedAt, fmt.Sprint(p.Sim().Now, " ", cause))
+		}
+	})
+	if !p.Interrupt("early") {
+		t.Error("expected the process to be interrupted")
+	}
+	sim.RunUntilDone()
+
+	if expected := []string{fmt.Sprint(time.Time{}, " early")}; fmt.Sprint(interruptedAt) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, interruptedAt)
+	}
+	if p.Interrupt("late") {
+		t.Error("expected a done process to not be interrupted")
+	}
+}
+
+func TestProcessKill(t *testing.T) {
+	sim := NewSimulation()
+
+	cleanedUp := false
+	reachedEnd := false
+	p := NewProcess(sim, func(p *Process, yield func(Command) bool) {
+		defer func() { cleanedUp = true }()
+		if !yield(Timeout(10 * time.Second)) {
+			return
+		}
+		reachedEnd = true
+	})
+	var joinedAt time.Time
+	p.Join(func(s *Simulation) { joinedAt = s.Now })
+	sim.After(time.Second, func(*Simulation) {
+		if !p.Kill() {
+			t.Error("expected the process to be killed")
+		}
+	})
+	sim.RunUntilDone()
+
+	if !p.Done() {
+		t.Error("expected the process to be done")
+	}
+	if !cleanedUp {
+		t.Error("expected the body of the process to return")
+	}
+	if reachedEnd {
+		t.Error("expected yield to return false in the killed process")
+	}
+	if expected := (time.Time{}).Add(time.Second); !joinedAt.Equal(expected) {
+		t.Errorf("expected join at %s, got %s", expected, joinedAt)
+	}
+	if !sim.Now.Equal(time.Time{}.Add(time.Second)) {
+		t.Errorf("expected the cancelled timeout to not move the clock, got %s", sim.Now)
+	}
+	if p.Kill() {
+		t.Error("expected a done process to not be killed again")
+	}
+}
+
+func TestProcessCannotKillItself(t *testing.T) {
+	sim := NewSimulation()
+	NewProcess(sim, func(p *Process, yield func(Command) bool) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("expected a panic")
+			}
+		}()
+		p.Kill()
+	})
+	sim.RunUntilDone()
+}
+
+func TestProcessJoin(t *testing.T) {
+	sim := NewSimulation()
+
+	worker := NewProcess(sim, func(p *Process, yield func(Command) bool) {
+		yield(Timeout(5 * time.Second))
+	})
+	var joinedAt []time.Time
+	for range 2 {
+		NewProcess(sim, func(p *Process, yield func(Command) bool) {
+			if !yield(Join(worker)) {
+				return
+			}
+			joinedAt = append(joinedAt, p.Sim().Now)
+
+			// Joining a process that is already done resumes right away.
+			if !yield(Join(worker)) {
+				return
+			}
+			joinedAt = append(joinedAt, p.Sim().Now)
+		})
+	}
+	sim.RunUntilDone()
+
+	done := (time.Time{}).Add(5 * time.Second)
+	if expected := []time.Time{done, done, done, done}; fmt.Sprint(joinedAt) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, joinedAt)
+	}
+
+	var lateJoin bool
+	worker.Join(func(*Simulation) { lateJoin = true })
+	sim.RunUntilDone()
+	if !lateJoin {
+		t.Error("expected joining a done process to run the action")
+	}
+}
+
+func TestProcessKillAnotherProcessWhileRunning(t *testing.T) {
+	sim := NewSimulation()
+	victim := NewProcess(sim, func(p *Process, yield func(Command) bool) {
+		for yield(Timeout(time.Second)) {
+		}
+	})
+	NewProcess(sim, func(p *Process, yield func(Command) bool) {
+		if !yield(Timeout(5 * time.Second)) {
+			return
+		}
+		victim.Kill()
+	})
+	sim.RunUntilDone()
+
+	if !victim.Done() {
+		t.Error("expected the victim to be done")
+	}
+}
+
+func TestProcessWaitUntil(t *testing.T) {
+	sim := NewSimulation()
+	c := NewCondition(sim)
+	level := 0
+
+	var resumedAt time.Time
+	NewProcess(sim, func(p *Process, yield func(Command) bool) {
+		if !yield(WaitUntil(c, func() bool { return level >= 3 })) {
+			return
+		}
+		resumedAt = p.Sim().Now
+	})
+	Ticker(sim, sim.Now.Add(time.Second), time.Second, func(*Simulation) {
+		level++
+		c.Broadcast()
+	})
+	sim.RunUntil(sim.Now.Add(10 * time.Second))
+
+	if expected := (time.Time{}).Add(3 * time.Second); !resumedAt.Equal(expected) {
+		t.Errorf("expected to resume at %s, got %s", expected, resumedAt)
+	}
+}
@@ -1,7 +1,6 @@
 package steps
 
 import (
-	"container/heap"
 	"fmt"
 	"time"
 )
@@ -13,102 +12,286 @@ type Event struct {
 
 	// Action is the function to call when the event is to be processed.
 	Action Action
+
+	// Priority orders events scheduled for the same time. Events with a lower Priority are processed first. The default is zero, so a negative Priority processes an event before other events at the same time, and a positive one after them. This also applies to events scheduled using Simulation.Immediately, and to actions woken up by a Condition, which are scheduled with the priority they were waiting with.
+	Priority int
 }
 
-// scheduledEvent represents a future scheduledEvent in the simulation.
-type scheduledEvent struct {
+// ScheduledEvent represents a future event in the simulation, as stored in a Queue.
+type ScheduledEvent struct {
 	ID    EventID
 	Event Event
+
+	// seq is incremented for each event scheduled to a simulation. It is used to sort events with the same time.
+	seq uint64
+
+	// immediate is set for events scheduled using Simulation.Immediately.
+	immediate bool
 }
 
 // String returns a string representation of the event.
-func (e scheduledEvent) String() string {
-	return fmt.Sprintf("scheduledEvent{ID: %d, When: %s}", e.ID, e.Event.When)
+func (e ScheduledEvent) String() string {
+	return fmt.Sprintf("ScheduledEvent{ID: %d, When: %s}", e.ID, e.Event.When)
 }
 
-// eventQueue is a type-safe heap of events. Events with the same time are sorted by order. Otherwise, they are sorted by time, smallest first.
+// Before reports whether e should be processed before o. Events are sorted by time, smallest first, and events with the same time by Priority, lowest first. Events with the same time and Priority that were scheduled using Simulation.Immediately come first, and otherwise they are sorted by the order they were scheduled.
+//
+// Every Queue implementation must pop events in the order defined by this method.
+func (e ScheduledEvent) Before(o ScheduledEvent) bool {
+	if !e.Event.When.Equal(o.Event.When) {
+		return e.Event.When.Before(o.Event.When)
+	}
+	if e.Event.Priority != o.Event.Priority {
+		return e.Event.Priority < o.Event.Priority
+	}
+	if e.immediate != o.immediate {
+		return e.immediate
+	}
+	return e.seq < o.seq
+}
+
+// Queue is a priority queue of scheduled events used by a Simulation to keep track of future events. See WithQueue for how to use a different implementation than the default one.
+//
+// Implementations must pop events in the order defined by ScheduledEvent.Before. They do not need to be safe for concurrent use.
+type Queue interface {
+	// Push adds an event to the queue. The ID of the event is guaranteed to be unique among the events in the queue.
+	Push(e ScheduledEvent)
+
+	// Pop removes the next event from the queue. It is only called on a non-empty queue.
+	Pop() ScheduledEvent
+
+	// Peek returns the next event in the queue without removing it. It is only called on a non-empty queue.
+	Peek() ScheduledEvent
+
+	// Remove removes an event from the queue. Returns true if the event was found and removed, false otherwise.
+	Remove(id EventID) bool
+
+	// Len returns the number of events in the queue.
+	Len() int
+
+	// Lookup returns the event with the given ID. Returns false if there is no such event in the queue.
+	Lookup(id EventID) (ScheduledEvent, bool)
+
+	// Update replaces the event that has the same ID as e with e, keeping the queue ordered. Returns true if the event was found and updated, false otherwise.
+	Update(e ScheduledEvent) bool
+}
+
+// NewHeapQueue creates a binary heap based Queue. This is the default queue used by a Simulation and a good choice for most simulations.
+func NewHeapQueue() Queue {
+	return newEventQueue()
+}
+
+// eventQueue is a type-safe binary heap of events. Events with the same time are sorted by order. Otherwise, they are sorted by time, smallest first.
+//
+// To keep scheduling free of allocations, it neither uses container/heap (which boxes every event into an interface) nor a map from event ID to heap index. Instead, it relies on event IDs being dense handles (see eventIDs) and keeps the heap index of every event in a slice indexed by the slot of its ID.
 type eventQueue struct {
-	heap eventsHeap
+	events []ScheduledEvent
+
+	// positions maps the slot of an event ID to the index of the event in events plus one. Zero means that there is no event with that slot in the queue.
+	positions []int
 }
 
 // newEventQueue creates a new event queue.
 func newEventQueue() *eventQueue {
-	return &eventQueue{
-		heap: eventsHeap{
-			IndexByID: make(map[EventID]int),
-		},
-	}
+	return &eventQueue{}
 }
 
 // Push adds an event to the queue.
-func (q *eventQueue) Push(e scheduledEvent) {
-	heap.Push(&q.heap, e)
+func (q *eventQueue) Push(e ScheduledEvent) {
+	slot := int(e.ID.slot())
+	if slot >= len(q.positions) {
+		q.positions = append(q.positions, make([]int, slot-len(q.positions)+1)...)
+	}
+	if q.positions[slot] != 0 {
+		panic(fmt.Sprintf("event with ID %d already exists", e.ID))
+	}
+	q.events = append(q.events, e)
+	q.positions[slot] = len(q.events)
+	q.up(len(q.events) - 1)
 }
 
 // Pop removes the next event from the queue.
-func (q *eventQueue) Pop() scheduledEvent {
-	return heap.Pop(&q.heap).(scheduledEvent)
+func (q *eventQueue) Pop() ScheduledEvent {
+	return q.removeAt(0)
 }
 
 // Peek returns the next event in the queue without removing it.
-func (q *eventQueue) Peek() scheduledEvent {
-	return q.heap.Events[0]
+func (q *eventQueue) Peek() ScheduledEvent {
+	return q.events[0]
 }
 
 // Remove removes an event from the queue. Returns true if the event was found and removed, false otherwise.
 func (q *eventQueue) Remove(id EventID) bool {
-	index, found := q.heap.IndexByID[id]
+	index, found := q.index(id)
 	if !found {
 		return false
 	}
-	heap.Remove(&q.heap, index)
+	q.removeAt(index)
 	return true
 }
 
 // Len returns the number of events in the queue.
 func (q *eventQueue) Len() int {
-	return q.heap.Len()
+	return len(q.events)
 }
 
-// A heap of events. Events with the same time are sorted by order. Otherwise, they are sorted by time, smallest first.
-type eventsHeap struct {
-	Events    []scheduledEvent
-	IndexByID map[EventID]int
+// Lookup returns the event with the given ID.
+func (q *eventQueue) Lookup(id EventID) (ScheduledEvent, bool) {
+	index, found := q.index(id)
+	if !found {
+		return ScheduledEvent{}, false
+	}
+	return q.events[index], true
 }
 
-func (h eventsHeap) Len() int {
-	sliceLen := len(h.Events)
-	if sliceLen != len(h.IndexByID) {
-		panic(fmt.Sprintf("len(h.Events) != len(h.IndexByID): %d != %d", sliceLen, len(h.IndexByID)))
+// Update replaces the event that has the same ID as e with e, fixing the heap in place.
+func (q *eventQueue) Update(e ScheduledEvent) bool {
+	index, found := q.index(e.ID)
+	if !found {
+		return false
 	}
-	return sliceLen
+	q.events[index] = e
+	q.fix(index)
+	return true
 }
-func (h eventsHeap) Less(i, j int) bool {
-	if h.Events[i].Event.When == h.Events[j].Event.When {
-		return h.Events[i].ID < h.Events[j].ID
+
+// index returns the index in events of the event with the given ID. The generation part of the ID must match, so stale IDs of events that have already left the queue are not found.
+func (q *eventQueue) index(id EventID) (int, bool) {
+	slot := int(id.slot())
+	if slot >= len(q.positions) || q.positions[slot] == 0 {
+		return 0, false
+	}
+	index := q.positions[slot] - 1
+	if q.events[index].ID != id {
+		return 0, false
 	}
-	return h.Events[i].Event.When.Before(h.Events[j].Event.When)
+	return index, true
 }
-func (h eventsHeap) Swap(i, j int) {
-	h.Events[i], h.Events[j] = h.Events[j], h.Events[i]
 
-	h.IndexByID[h.Events[i].ID] = i
-	h.IndexByID[h.Events[j].ID] = j
+// removeAt removes the event at index i from the heap.
+func (q *eventQueue) removeAt(i int) ScheduledEvent {
+	last := len(q.events) - 1
+	if i != last {
+		q.swap(i, last)
+	}
+	e := q.events[last]
+	q.events[last] = ScheduledEvent{} // Don't keep a reference to the action.
+	q.events = q.events[:last]
+	q.positions[e.ID.slot()] = 0
+	if i != last {
+		q.fix(i)
+	}
+	return e
 }
 
-func (h *eventsHeap) Push(xUntyped any) {
-	x := xUntyped.(scheduledEvent)
-	if _, found := h.IndexByID[x.ID]; found {
-		panic(fmt.Sprintf("event with ID %d already exists", x.ID))
+// fix re-establishes the heap ordering after the event at index i has changed.
+func (q *eventQueue) fix(i int) {
+	if !q.down(i) {
+		q.up(i)
 	}
-	h.Events = append(h.Events, x)
-	h.IndexByID[x.ID] = len(h.Events) - 1
 }
 
-func (h *eventsHeap) Pop() any {
-	n := len(h.Events)
-	x := h.Events[n-1]
-	h.Events = h.Events[0 : n-1]
-	delete(h.IndexByID, x.ID)
-	return x
+func (q *eventQueue) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !q.events[i].Before(q.events[parent]) {
+			break
+		}
+		q.swap(i, parent)
+		i = parent
+	}
+}
+
+// down moves the event at index i down the heap. Returns true if it was moved.
+func (q *eventQueue) down(i int) bool {
+	start := i
+	n := len(q.events)
+	for {
+		smallest := 2*i + 1
+		if smallest >= n {
+			break
+		}
+		if right := smallest + 1; right < n && q.events[right].Before(q.events[smallest]) {
+			smallest = right
+		}
+		if !q.events[smallest].Before(q.events[i]) {
+			break
+		}
+		q.swap(i, smallest)
+		i = smallest
+	}
+	return i > start
+}
+
+func (q *eventQueue) swap(i, j int) {
+	q.events[i], q.events[j] = q.events[j], q.events[i]
+	q.positions[q.events[i].ID.slot()] = i + 1
+	q.positions[q.events[j].ID.slot()] = j + 1
+}
+
+// tombstones keeps track of which events in a queue are live. It is used by queues that cannot cheaply remove or update an arbitrary event, and instead lazily skip events that have been removed or replaced when they are popped.
+type tombstones struct {
+	// live maps the ID of every live event to the event. An event stored in the queue is live only if it is identical to the one in live. Since every scheduled event gets a unique seq, comparing seq is enough.
+	live map[EventID]ScheduledEvent
+}
+
+func newTombstones() tombstones {
+	return tombstones{live: make(map[EventID]ScheduledEvent)}
+}
+
+// add marks an event as live.
+func (t *tombstones) add(e ScheduledEvent) {
+	if _, found := t.live[e.ID]; found {
+		panic(fmt.Sprintf("event with ID %d already exists", e.ID))
+	}
+	t.live[e.ID] = e
+}
+
+// remove marks an event as removed. Returns true if the event was live.
+func (t *tombstones) remove(id EventID) bool {
+	if _, found := t.live[id]; !found {
+		return false
+	}
+	delete(t.live, id)
+	return true
+}
+
+// lookup returns the live event with the given ID.
+func (t *tombstones) lookup(id EventID) (ScheduledEvent, bool) {
+	e, found := t.live[id]
+	return e, found
+}
+
+// isLive returns whether an event stored in the queue is live.
+func (t *tombstones) isLive(e ScheduledEvent) bool {
+	live, found := t.live[e.ID]
+	return found && live.seq == e.seq
+}
+
+// forget is called when an event is taken out of the underlying queue. Returns true if the event was live.
+func (t *tombstones) forget(e ScheduledEvent) bool {
+	if !t.isLive(e) {
+		return false
+	}
+	delete(t.live, e.ID)
+	return true
+}
+
+// len returns the number of live events.
+func (t *tombstones) len() int {
+	return len(t.live)
+}
+
+// eventKey maps an event time to an integer key relative to origin. Keys are only used to place events in buckets; they are monotonic in time but saturate for times more than ~292 years from origin. Queues must therefore not assume that events with the same key are in the same order as their times, and either order them using ScheduledEvent.Before (like the calendar queue) or keep saturated keys apart (like the ladder queue).
+func eventKey(origin, when time.Time) int64 {
+	return int64(when.Sub(origin))
+}
+
+// floorDiv returns a/b rounded towards negative infinity. b must be positive.
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b < 0 {
+		q--
+	}
+	return q
 }
@@ -1,6 +1,8 @@
 package steps
 
 import (
+	"fmt"
+	"maps"
 	"math/rand/v2"
 	"slices"
 	"testing"
@@ -11,10 +13,10 @@ func TestQueueTimeBasedOrdering(t *testing.T) {
 	var now time.Time
 
 	// These events are in order of schedule priority.
-	events := []scheduledEvent{
-		{ID: 1, Event: Event{When: now.Add(time.Second), Action: nil}},
-		{ID: 2, Event: Event{When: now.Add(time.Second * 2), Action: nil}},
-		{ID: 3, Event: Event{When: now.Add(time.Second * 2), Action: nil}},
+	events := []ScheduledEvent{
+		{ID: 1, Event: Event{When: now.Add(time.Second), Action: nil}, seq: 1},
+		{ID: 2, Event: Event{When: now.Add(time.Second * 2), Action: nil}, seq: 2},
+		{ID: 3, Event: Event{When: now.Add(time.Second * 2), Action: nil}, seq: 3},
 	}
 
 	// Subtest that adds the events to the queue and then pops them in order.
@@ -69,15 +71,15 @@ func TestQueueTimeBasedOrdering(t *testing.T) {
 	})
 }
 
-func eventsAreEqual(a, b scheduledEvent) bool {
-	return a.ID == b.ID && a.Event.When == b.Event.When
+func eventsAreEqual(a, b ScheduledEvent) bool {
+	return a.ID == b.ID && a.Event.When == b.Event.When && a.seq == b.seq
 }
 
 func TestQueueLen(t *testing.T) {
 	queue := newEventQueue()
-	queue.Push(scheduledEvent{ID: 1, Event: Event{When: time.Now().Add(time.Second), Action: nil}})
-	queue.Push(scheduledEvent{ID: 2, Event: Event{When: time.Now().Add(time.Second * 2), Action: nil}})
-	queue.Push(scheduledEvent{ID: 3, Event: Event{When: time.Now().Add(time.Second * 2), Action: nil}})
+	queue.Push(ScheduledEvent{ID: 1, Event: Event{When: time.Now().Add(time.Second), Action: nil}})
+	queue.Push(ScheduledEvent{ID: 2, Event: Event{When: time.Now().Add(time.Second * 2), Action: nil}})
+	queue.Push(ScheduledEvent{ID: 3, Event: Event{When: time.Now().Add(time.Second * 2), Action: nil}})
 
 	if queue.Len() != 3 {
 		t.Errorf("expected queue length 3, got %d", queue.Len())
@@ -87,7 +89,7 @@ func TestQueueLen(t *testing.T) {
 func TestQueueRemoveOfExistingEvent(t *testing.T) {
 	queue := newEventQueue()
 
-	event := scheduledEvent{ID: 42, Event: Event{When: time.Now().Add(time.Second), Action: nil}}
+	event := ScheduledEvent{ID: 42, Event: Event{When: time.Now().Add(time.Second), Action: nil}}
 	queue.Push(event)
 
 	if removed := queue.Remove(event.ID); !removed {
@@ -102,7 +104,7 @@ func TestQueueRemoveOfExistingEvent(t *testing.T) {
 func TestQueueRemoveOfMissingEvent(t *testing.T) {
 	queue := newEventQueue()
 
-	event := scheduledEvent{ID: 42, Event: Event{When: time.Now().Add(time.Second), Action: nil}}
+	event := ScheduledEvent{ID: 42, Event: Event{When: time.Now().Add(time.Second), Action: nil}}
 	queue.Push(event)
 
 	missingID := event.ID - 1
@@ -114,3 +116,287 @@ func TestQueueRemoveOfMissingEvent(t *testing.T) {
 		t.Errorf("expected queue length 2, got %d", queue.Len())
 	}
 }
+
+// queueImplementations are all the Queue implementations that should behave identically.
+var queueImplementations = []struct {
+	name     string
+	newQueue func() Queue
+}{
+	{"Heap", NewHeapQueue},
+	{"Calendar", NewCalendarQueue},
+	{"Ladder", NewLadderQueue},
+	{"PairingHeap", NewPairingHeap},
+}
+
+func TestQueueImplementationsOrdering(t *testing.T) {
+	for _, impl := range queueImplementations {
+		t.Run(impl.name, func(t *testing.T) {
+			rnd := rand.New(rand.NewPCG(1, 2))
+			var start time.Time
+
+			queue := impl.newQueue()
+			var expected []ScheduledEvent
+			nextID := EventID(0)
+			push := func(when time.Time) {
+				e := ScheduledEvent{ID: nextID, Event: Event{When: when}, seq: uint64(nextID)}
+				nextID++
+				queue.Push(e)
+				expected = append(expected, e)
+			}
+
+			// Lots of events, many of them at the same point in time to verify FIFO tie-breaking.
+			for range 1000 {
+				push(start.Add(time.Duration(rnd.IntN(200)) * time.Second))
+			}
+
+			// Remove some of them.
+			for range 100 {
+				i := rnd.IntN(len(expected))
+				if !queue.Remove(expected[i].ID) {
+					t.Fatalf("expected %v to be removed", expected[i])
+				}
+				if queue.Remove(expected[i].ID) {
+					t.Fatalf("expected %v to already have been removed", expected[i])
+				}
+				expected = slices.Delete(expected, i, i+1)
+			}
+
+			// Interleave pops with pushes in the future of the last popped event, like a simulation would.
+			slices.SortFunc(expected, compareEvents)
+			var popped []ScheduledEvent
+			for queue.Len() > 0 {
+				if queue.Len() != len(expected)-len(popped) {
+					t.Fatalf("expected queue length %d, got %d", len(expected)-len(popped), queue.Len())
+				}
+				peeked := queue.Peek()
+				e := queue.Pop()
+				if !eventsAreEqual(peeked, e) {
+					t.Fatalf("peeked %v, but popped %v", peeked, e)
+				}
+				popped = append(popped, e)
+				if len(popped) < 2000 {
+					for range rnd.IntN(3) {
+						push(e.Event.When.Add(time.Duration(rnd.IntN(100)) * time.Second))
+					}
+					slices.SortFunc(expected[len(popped):], compareEvents)
+				}
+			}
+
+			if len(popped) != len(expected) {
+				t.Fatalf("expected %d events, got %d", len(expected), len(popped))
+			}
+			for i := range expected {
+				if !eventsAreEqual(popped[i], expected[i]) {
+					t.Fatalf("event %d: expected %v, got %v", i, expected[i], popped[i])
+				}
+			}
+		})
+	}
+}
+
+func TestQueueImplementationsUpdate(t *testing.T) {
+	for _, impl := range queueImplementations {
+		t.Run(impl.name, func(t *testing.T) {
+			rnd := rand.New(rand.NewPCG(1, 2))
+			var start time.Time
+
+			queue := impl.newQueue()
+			expected := make(map[EventID]ScheduledEvent)
+			seq := uint64(0)
+			for id := range EventID(500) {
+				e := ScheduledEvent{ID: id, Event: Event{When: start.Add(time.Duration(rnd.IntN(100)) * time.Second)}, seq: seq}
+				seq++
+				queue.Push(e)
+				expected[id] = e
+			}
+
+			for range 1000 {
+				id := EventID(rnd.IntN(500))
+				e := expected[id]
+				e.Event.When = start.Add(time.Duration(rnd.IntN(100)) * time.Second)
+				e.seq = seq
+				seq++
+				if !queue.Update(e) {
+					t.Fatalf("expected %v to be updated", e)
+				}
+				expected[id] = e
+				if looked, found := queue.Lookup(id); !found || !eventsAreEqual(looked, e) {
+					t.Fatalf("expected to look up %v, got %v", e, looked)
+				}
+			}
+			if queue.Update(ScheduledEvent{ID: 500}) {
+				t.Error("expected missing event to not be updated")
+			}
+
+			sorted := slices.SortedFunc(maps.Values(expected), compareEvents)
+			if queue.Len() != len(sorted) {
+				t.Fatalf("expected queue length %d, got %d", len(sorted), queue.Len())
+			}
+			for _, e := range sorted {
+				if popped := queue.Pop(); !eventsAreEqual(popped, e) {
+					t.Fatalf("expected %v, got %v", e, popped)
+				}
+			}
+		})
+	}
+}
+
+func TestQueueImplementationsRemoveMissing(t *testing.T) {
+	for _, impl := range queueImplementations {
+		t.Run(impl.name, func(t *testing.T) {
+			queue := impl.newQueue()
+			queue.Push(ScheduledEvent{ID: 1, Event: Event{When: time.Now()}})
+
+			if queue.Remove(2) {
+				t.Error("expected event to not be removed")
+			}
+			if queue.Len() != 1 {
+				t.Errorf("expected queue length 1, got %d", queue.Len())
+			}
+		})
+	}
+}
+
+func TestQueueImplementationsFarFromFirstEvent(t *testing.T) {
+	for _, impl := range queueImplementations {
+		t.Run(impl.name, func(t *testing.T) {
+			sim := NewSimulation(WithQueue(impl.newQueue()))
+			date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			var order []time.Duration
+			record := func(sim *Simulation) { order = append(order, sim.Now.Sub(date)) }
+			sim.Immediately(func(*Simulation) {})
+			sim.At(date.Add(3*time.Second), func(sim *Simulation) {
+				record(sim)
+				sim.At(date.Add(4*time.Second), record)
+			})
+			sim.At(date.Add(5*time.Second), record)
+			sim.RunUntilDone()
+
+			expected := []time.Duration{3 * time.Second, 4 * time.Second, 5 * time.Second}
+			if fmt.Sprint(order) != fmt.Sprint(expected) {
+				t.Errorf("expected %v, got %v", expected, order)
+			}
+		})
+	}
+}
+
+func TestQueueImplementationsOrderingOverCenturies(t *testing.T) {
+	for _, impl := range queueImplementations {
+		t.Run(impl.name, func(t *testing.T) {
+			rnd := rand.New(rand.NewPCG(3, 4))
+			queue := impl.newQueue()
+			reference := NewHeapQueue()
+
+			const year = 365 * 24 * time.Hour
+			var now time.Time
+			nextID := EventID(0)
+			push := func(when time.Time) {
+				e := ScheduledEvent{ID: nextID, Event: Event{When: when}, seq: uint64(nextID)}
+				nextID++
+				queue.Push(e)
+				reference.Push(e)
+			}
+			for range 100 {
+				push(now.Add(time.Duration(rnd.IntN(1000)) * year))
+			}
+			for popped := 0; reference.Len() > 0; popped++ {
+				expected, got := reference.Pop(), queue.Pop()
+				if !eventsAreEqual(expected, got) {
+					t.Fatalf("event %d: expected %v, got %v", popped, expected, got)
+				}
+				now = got.Event.When
+				if popped < 1000 {
+					for range rnd.IntN(3) {
+						push(now.Add(time.Duration(rnd.IntN(400)) * year))
+					}
+				}
+			}
+			if queue.Len() != 0 {
+				t.Errorf("expected the queue to be empty, got %d events", queue.Len())
+			}
+		})
+	}
+}
+
+func TestSimulationWithQueue(t *testing.T) {
+	for _, impl := range queueImplementations {
+		t.Run(impl.name, func(t *testing.T) {
+			sim := NewSimulation(WithQueue(impl.newQueue()))
+
+			var timesCalled []time.Time
+			Ticker(sim, sim.Now, 1*time.Second, func(s *Simulation) {
+				timesCalled = append(timesCalled, s.Now)
+			})
+			Ticker(sim, sim.Now, 3*time.Second, func(s *Simulation) {
+				timesCalled = append(timesCalled, s.Now)
+			})
+			sim.RunUntil(sim.Now.Add(20 * time.Second))
+
+			if expectedTimesCalled := 28; len(timesCalled) != expectedTimesCalled {
+				t.Errorf("expected %d times called, got %d", expectedTimesCalled, len(timesCalled))
+			}
+			if !slices.IsSortedFunc(timesCalled, time.Time.Compare) {
+				t.Errorf("events were not processed in order: %v", timesCalled)
+			}
+		})
+	}
+}
+
+// BenchmarkQueues compares the Queue implementations using the classic "hold" model: the queue is kept at a constant size, and every popped event is replaced by a new one in its future.
+func BenchmarkQueues(b *testing.B) {
+	distributions := []struct {
+		name string
+		next func(r *rand.Rand) time.Duration
+	}{
+		{"Exponential", func(r *rand.Rand) time.Duration { return time.Duration(r.ExpFloat64() * float64(time.Second)) }},
+		{"Uniform", func(r *rand.Rand) time.Duration { return time.Duration(r.Int64N(int64(2 * time.Second))) }},
+		{"Bimodal", func(r *rand.Rand) time.Duration {
+			if r.IntN(10) == 0 {
+				return time.Duration(r.Int64N(int64(time.Hour)))
+			}
+			return time.Duration(r.Int64N(int64(time.Second)))
+		}},
+	}
+	for _, size := range []int{100, 10_000, 1_000_000} {
+		for _, dist := range distributions {
+			for _, impl := range queueImplementations {
+				b.Run(fmt.Sprintf("Size=%d/%s/%s", size, dist.name, impl.name), func(b *testing.B) {
+					rnd := rand.New(rand.NewPCG(1, 2))
+					queue := impl.newQueue()
+					var now time.Time
+					nextID := EventID(0)
+					for range size {
+						queue.Push(ScheduledEvent{ID: nextID, Event: Event{When: now.Add(dist.next(rnd))}, seq: uint64(nextID)})
+						nextID++
+					}
+
+					b.ResetTimer()
+					for range b.N {
+						e := queue.Pop()
+						// Reuse the ID of the popped event, like a Simulation would.
+						queue.Push(ScheduledEvent{ID: e.ID, Event: Event{When: e.Event.When.Add(dist.next(rnd))}, seq: uint64(nextID)})
+						nextID++
+					}
+				})
+			}
+		}
+	}
+}
+
+// BenchmarkSimulationQueues compares the Queue implementations when used by a Simulation.
+func BenchmarkSimulationQueues(b *testing.B) {
+	for _, impl := range queueImplementations {
+		b.Run(impl.name, func(b *testing.B) {
+			sim := NewSimulation(WithQueue(impl.newQueue()))
+			for i := range 1000 {
+				Ticker(sim, sim.Now, time.Duration(i+1)*time.Millisecond, func(*Simulation) {})
+			}
+
+			b.ResetTimer()
+			for range b.N {
+				sim.Step()
+			}
+		})
+	}
+}
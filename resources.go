@@ -1,8 +1,9 @@
 package steps
 
 import (
-	"container/heap"
 	"fmt"
+	"iter"
+	"slices"
 	"time"
 )
 
@@ -11,162 +12,611 @@ type ConditionActionID int
 // Condition is a condition that can be used to synchronize actions. Multiple actions can be waiting for the same condition, and be triggered by a Signal or Broadcast, similarly to sync.Cond.
 type Condition struct {
 	sim    *Simulation
-	heap   *conditionHeap
+	queue  *conditionQueue
 	nextID ConditionActionID
+
+	// predicates is the number of waiting actions that have a predicate. If there are none, Signal and Broadcast can simply pop the queue.
+	predicates int
 }
 
 // NewCondition creates a new condition.
 func NewCondition(sim *Simulation) *Condition {
-	return &Condition{
-		sim:  sim,
-		heap: newConditionHeap(),
+	c := &Condition{
+		sim:   sim,
+		queue: newConditionQueue(),
 	}
+	sim.trackCondition(c)
+	return c
 }
 
+// Wait makes an action wait for the condition to be signaled. It returns an ID that can be used to cancel the wait.
 func (c *Condition) Wait(a Action) ConditionActionID {
+	return c.WaitWithPriority(0, a)
+}
+
+// WaitWithPriority is like Wait, but with a priority. Actions with a lower priority are woken up first, and the woken up action is scheduled with the priority (see Event.Priority). Actions with the same priority are woken up in the order they were waiting.
+func (c *Condition) WaitWithPriority(priority int, a Action) ConditionActionID {
+	id := c.nextID
+	c.queue.push(conditionActionItem{ID: id, Action: a, Priority: priority})
+	c.nextID++
+	return id
+}
+
+// WaitUntil makes an action wait for the condition to be signaled while pred returns true. pred is called when the condition is signaled or broadcast. If it returns false, the action keeps waiting, in its original place in line, and Signal wakes up the next action in line instead. This makes it possible to use Broadcast to notify about state changes, without every action having to check its own predicate and wait again. It returns an ID that can be used to cancel the wait.
+//
+// Note that pred is called in the order the actions are waiting, before any of the woken up actions have run.
+func (c *Condition) WaitUntil(pred func() bool, a Action) ConditionActionID {
 	id := c.nextID
-	heap.Push(c.heap, conditionActionItem{ID: id, Action: a})
+	c.queue.push(conditionActionItem{ID: id, Action: a, Predicate: pred})
 	c.nextID++
+	c.predicates++
 	return id
 }
 
 // Cancel cancels an action waiting for this condition. Returns true if the action was found and removed, false otherwise (e.g. it was already executed, never existed, or was previously cancelled).
 func (c *Condition) Cancel(id ConditionActionID) bool {
-	index, found := c.heap.IndexByID[id]
+	node, found := c.queue.byID[id]
 	if !found {
 		return false
 	}
-	heap.Remove(c.heap, index)
+	c.queue.remove(node)
+	c.forget(node.conditionActionItem)
 	return true
 }
 
-// Signal wakes up one action waiting for this condition. Actions are woken up in the order they were waiting.
+// Waiting returns the number of actions waiting for this condition.
+func (c *Condition) Waiting() int {
+	return len(c.queue.byID)
+}
+
+// Signal wakes up one action waiting for this condition. Actions are woken up by priority, and then in the order they were waiting.
 func (c *Condition) Signal() {
-	if c.heap.Len() == 0 {
+	if c.predicates > 0 {
+		for node := range c.queue.all(c.nextID) {
+			if node.Predicate == nil || node.Predicate() {
+				c.signalID(node.ID)
+				return
+			}
+		}
 		return
 	}
-
-	item := heap.Pop(c.heap).(conditionActionItem)
-	c.sim.Schedule(Event{
-		When:   time.Time{}, // As soon as possible.
-		Action: item.Action,
-	})
+	if c.queue.head != nil {
+		c.wake(c.queue.head)
+	}
 }
 
-// Broadcast wakes up all actions waiting for this condition. Actions are woken up in the order they were waiting.
+// Broadcast wakes up all actions waiting for this condition. Actions are woken up by priority, and then in the order they were waiting.
 func (c *Condition) Broadcast() {
-	for c.heap.Len() > 0 {
-		// It's important that we iterate over the heap in order to schedule in a FIFO manner.
-		item := heap.Pop(c.heap).(conditionActionItem)
-		c.sim.Schedule(Event{
-			When:   time.Time{}, // As soon as possible.
-			Action: item.Action,
-		})
+	if c.predicates > 0 {
+		for node := range c.queue.all(c.nextID) {
+			if node.Predicate == nil || node.Predicate() {
+				c.signalID(node.ID)
+			}
+		}
+		return
+	}
+	// It's important that we wake up the actions in order to schedule in a FIFO manner.
+	for c.queue.head != nil {
+		c.wake(c.queue.head)
+	}
+}
+
+// front returns the ID of the action that would be woken up by Signal.
+func (c *Condition) front() (ConditionActionID, bool) {
+	if c.queue.head == nil {
+		return 0, false
+	}
+	return c.queue.head.ID, true
+}
+
+// ordered returns the IDs of the waiting actions, in the order they would be woken up. Actions that stop waiting while iterating are skipped, and actions that start waiting while iterating are not included.
+func (c *Condition) ordered() iter.Seq[ConditionActionID] {
+	return func(yield func(ConditionActionID) bool) {
+		for node := range c.queue.all(c.nextID) {
+			if !yield(node.ID) {
+				return
+			}
+		}
+	}
+}
+
+// signalID wakes up a specific action waiting for this condition. Returns false if the action isn't waiting.
+func (c *Condition) signalID(id ConditionActionID) bool {
+	node, found := c.queue.byID[id]
+	if !found {
+		return false
+	}
+	c.wake(node)
+	return true
+}
+
+// forget updates the bookkeeping for an action that is no longer waiting.
+func (c *Condition) forget(item conditionActionItem) {
+	if item.Predicate != nil {
+		c.predicates--
 	}
 }
 
-// conditionActionItem is an item in the condition heap.
+// wake removes a woken up action from the queue, and schedules it to run as soon as possible.
+func (c *Condition) wake(node *conditionNode) {
+	c.queue.remove(node)
+	c.forget(node.conditionActionItem)
+	c.sim.schedule(Event{When: c.sim.Now, Action: node.Action, Priority: node.Priority}, true)
+}
+
+// conditionActionItem is an action waiting for a condition.
 type conditionActionItem struct {
-	ID     ConditionActionID
-	Action Action
+	ID        ConditionActionID
+	Action    Action
+	Priority  int
+	Predicate func() bool
 }
 
-// A heap of actions, ordered by ConditionActionID.
-type conditionHeap struct {
-	items     []conditionActionItem
-	IndexByID map[ConditionActionID]int
+// conditionQueue holds the actions waiting for a condition, ordered by priority and then ConditionActionID. It is a doubly linked list, so that actions can be removed from anywhere and the queue can be walked in order without sorting it. Since IDs are handed out in increasing order, a new action always goes last among the actions with the same priority.
+type conditionQueue struct {
+	head *conditionNode
+	byID map[ConditionActionID]*conditionNode
+
+	// last holds the last action of each priority in the queue, and priorities holds those priorities in increasing order.
+	last       map[int]*conditionNode
+	priorities []int
 }
 
-func newConditionHeap() *conditionHeap {
-	return &conditionHeap{
-		IndexByID: make(map[ConditionActionID]int),
+// conditionNode is an action in a conditionQueue.
+type conditionNode struct {
+	conditionActionItem
+	prev, next *conditionNode
+
+	// removed is set once the node has been removed from the queue. A removed node keeps its next pointer, so that the queue can be walked while nodes are being removed.
+	removed bool
+}
+
+func newConditionQueue() *conditionQueue {
+	return &conditionQueue{
+		byID: make(map[ConditionActionID]*conditionNode),
+		last: make(map[int]*conditionNode),
 	}
 }
 
-func (h conditionHeap) Len() int {
-	sliceLen := len(h.items)
-	if sliceLen != len(h.IndexByID) {
-		panic(fmt.Sprintf("len(h.Events) != len(h.IndexByID): %d != %d", sliceLen, len(h.IndexByID)))
+// push adds an item last among the items with the same priority. Its ID must be higher than the IDs of all items in the queue.
+func (q *conditionQueue) push(item conditionActionItem) {
+	if _, found := q.byID[item.ID]; found {
+		panic(fmt.Sprintf("action with ID %d already exists", item.ID))
+	}
+	node := &conditionNode{conditionActionItem: item}
+	q.byID[item.ID] = node
+
+	after, found := q.last[item.Priority]
+	if !found {
+		i, _ := slices.BinarySearch(q.priorities, item.Priority)
+		if i > 0 {
+			after = q.last[q.priorities[i-1]]
+		}
+		q.priorities = slices.Insert(q.priorities, i, item.Priority)
+	}
+	q.last[item.Priority] = node
+
+	if after == nil {
+		node.next = q.head
+		q.head = node
+	} else {
+		node.prev = after
+		node.next = after.next
+		after.next = node
+	}
+	if node.next != nil {
+		node.next.prev = node
 	}
-	return sliceLen
 }
-func (h conditionHeap) Less(i, j int) bool {
-	return h.items[i].ID < h.items[j].ID
+
+// remove removes a node from the queue.
+func (q *conditionQueue) remove(node *conditionNode) {
+	delete(q.byID, node.ID)
+	if q.last[node.Priority] == node {
+		if node.prev != nil && node.prev.Priority == node.Priority {
+			q.last[node.Priority] = node.prev
+		} else {
+			delete(q.last, node.Priority)
+			i, _ := slices.BinarySearch(q.priorities, node.Priority)
+			q.priorities = slices.Delete(q.priorities, i, i+1)
+		}
+	}
+
+	if node.prev == nil {
+		q.head = node.next
+	} else {
+		node.prev.next = node.next
+	}
+	if node.next != nil {
+		node.next.prev = node.prev
+	}
+	node.prev = nil
+	node.removed = true
 }
-func (h conditionHeap) Swap(i, j int) {
-	h.items[i], h.items[j] = h.items[j], h.items[i]
 
-	h.IndexByID[h.items[i].ID] = i
-	h.IndexByID[h.items[j].ID] = j
+// all returns the nodes in the queue in order, skipping those with an ID of before or higher. It is safe to remove nodes while iterating, in which case they are skipped, and nodes added while iterating are skipped if before is the next ID to be handed out when starting.
+func (q *conditionQueue) all(before ConditionActionID) iter.Seq[*conditionNode] {
+	return func(yield func(*conditionNode) bool) {
+		for node := q.head; node != nil; node = node.next {
+			if node.removed || node.ID >= before {
+				continue
+			}
+			if !yield(node) {
+				return
+			}
+		}
+	}
 }
 
-func (h *conditionHeap) Push(xUntyped any) {
-	x := xUntyped.(conditionActionItem)
-	if _, found := h.IndexByID[x.ID]; found {
-		panic(fmt.Sprintf("event with ID %d already exists", x.ID))
+// Request is a pending or completed request to a resource, such as a Put or Get on a Store or Container, a message sent to a Mailbox or a lock of an RWLock, which can be used to stop waiting for the resource. See the resource for when a request that has been served can still be cancelled.
+type Request struct {
+	// cancel cancels the request. A nil cancel means the request has been served and cannot be cancelled.
+	cancel func() bool
+}
+
+func newRequest(cancel func() bool) *Request {
+	return &Request{cancel: cancel}
+}
+
+// Cancel stops waiting for the resource. Returns true if the request was cancelled, in which case its action will never run. Returns false if the request was already cancelled, or has already been served, in which case its action runs (or has run) as usual.
+func (r *Request) Cancel() bool {
+	if r.cancel == nil {
+		return false
+	}
+	return r.cancel()
+}
+
+// Fairness decides in which order actions waiting for a resource are served when they need different amounts of it. See WithFairness.
+type Fairness int
+
+const (
+	// FairnessFIFO serves waiting actions strictly in the order they started waiting. An action that needs more than is available blocks everyone behind it, even if they need less (head-of-line blocking). No action is ever starved. This is the default.
+	FairnessFIFO Fairness = iota
+
+	// FairnessFirstFit serves the first waiting action whose need can be met, allowing actions that need less to bypass those that need more. This keeps the resource busier, but an action that needs a lot can be starved by a steady stream of actions needing less.
+	FairnessFirstFit
+)
+
+// ResourceOption configures a resource, such as a CountingSemaphore. WithStats applies to every resource, while the other options only apply to some of them, as documented by each option. Creating a resource with an option that doesn't apply to it panics, rather than silently ignoring the option.
+type ResourceOption func(*resourceOptions)
+
+// resourceOptions holds the configuration of a resource.
+type resourceOptions struct {
+	fairness     Fairness
+	stats        bool
+	rwPreference RWPreference
+	overflow     Overflow
+
+	// given holds the names of the options given, except for WithStats, to check that they apply to the resource.
+	given []string
+}
+
+// newResourceOptions applies opts to the configuration of a resource. Panics if an option other than WithStats isn't one of supported, since it would otherwise be ignored.
+func newResourceOptions(resource string, opts []ResourceOption, supported ...string) resourceOptions {
+	var o resourceOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	for _, name := range o.given {
+		if !slices.Contains(supported, name) {
+			panic(fmt.Sprintf("%s does not apply to a %s", name, resource))
+		}
+	}
+	return o
+}
+
+// WithFairness sets the order in which a resource serves waiting actions that need different amounts of it. The default is FairnessFIFO. It applies to a CountingSemaphore, PriorityResource, PreemptiveResource and Container.
+func WithFairness(f Fairness) ResourceOption {
+	return func(o *resourceOptions) {
+		o.fairness = f
+		o.given = append(o.given, "WithFairness")
 	}
-	h.items = append(h.items, x)
-	h.IndexByID[x.ID] = len(h.items) - 1
 }
 
-func (h *conditionHeap) Pop() any {
-	n := len(h.items)
-	x := h.items[n-1]
-	h.items = h.items[0 : n-1]
-	delete(h.IndexByID, x.ID)
-	return x
+// WithStats makes a resource record statistics about how it is used, such as its utilization and how long requests wait for it. The statistics are retrieved using the Stats method of the resource. Statistics are not recorded by default, since that costs some memory and time for every request.
+func WithStats() ResourceOption {
+	return func(o *resourceOptions) {
+		o.stats = true
+	}
 }
 
 // CountingSemaphore is a semaphore that can be used to synchronize actions and limit the number of concurrent actions (in the simulation sense). It is a counting semaphore with a maximum of count.
+//
+// Actions can acquire more than one unit of the semaphore at a time using AcquireN, for example to model jobs needing several CPU cores. How actions needing different amounts are served is decided by WithFairness.
 type CountingSemaphore struct {
 	sim *Simulation
 
 	max       int
 	executing int
 
+	fairness Fairness
+
 	readyToExecute *Condition
+
+	// waiting holds the acquisitions waiting for readyToExecute, to know how many units each of them needs.
+	waiting map[ConditionActionID]*Acquisition
+
+	// holders holds the acquisitions holding units, in the order they were handed them, so that units released using ReleaseN can be taken from them.
+	holders []*Acquisition
+
+	// onGranted is called when units are handed over to an acquisition, before its action runs. It is used by resources built on the semaphore to keep track of holders.
+	onGranted func(acq *Acquisition)
+
+	// stats records statistics, if enabled using WithStats.
+	stats *statsRecorder
 }
 
 // NewCountingSemaphore creates a new counting semaphore.
-func NewCountingSemaphore(sim *Simulation, count int) *CountingSemaphore {
+func NewCountingSemaphore(sim *Simulation, count int, opts ...ResourceOption) *CountingSemaphore {
+	return newCountingSemaphore(sim, count, newResourceOptions("CountingSemaphore", opts, "WithFairness"))
+}
+
+func newCountingSemaphore(sim *Simulation, count int, o resourceOptions) *CountingSemaphore {
 	if count < 1 {
 		panic("count must be at least 1")
 	}
-	return &CountingSemaphore{
+	s := &CountingSemaphore{
 		sim:            sim,
 		max:            count,
 		executing:      0,
+		fairness:       o.fairness,
 		readyToExecute: NewCondition(sim),
+		waiting:        make(map[ConditionActionID]*Acquisition),
 	}
+	s.stats = newStatsRecorder(sim, o, float64(count), func() (float64, int) {
+		return float64(s.executing), s.readyToExecute.Waiting()
+	})
+	return s
+}
+
+// Stats returns statistics about how the semaphore has been used, where a unit held counts as being in use. Returns the zero ResourceStats unless the semaphore was created using WithStats.
+func (s *CountingSemaphore) Stats() ResourceStats {
+	return s.stats.stats()
 }
 
 // Acquire acquires the semaphore. If the semaphore is already acquired, the action will be scheduled to run when the semaphore is released. Do not forget to call Release() when the action is done (unless you want to hold the semaphore for longer).
-func (s *CountingSemaphore) Acquire(a Action) {
-	f := func(sim *Simulation) {
-		if s.executing >= s.max {
-			// Too many actions are being executed. Wait for the semaphore to be released.
+//
+// The returned Acquisition can be used to stop waiting for the semaphore.
+func (s *CountingSemaphore) Acquire(a Action) *Acquisition {
+	return s.AcquireN(1, a)
+}
 
-			s.readyToExecute.Wait(a)
-			return
-		}
-		s.executing++
-		a(sim)
+// AcquireN is like Acquire, but acquires n units of the semaphore at once. The action runs once all n units are available, and they must be released using ReleaseN(n). Panics if n is less than one or more than the count of the semaphore, since such an acquisition could never succeed.
+func (s *CountingSemaphore) AcquireN(n int, a Action) *Acquisition {
+	return s.acquire(n, 0, a)
+}
+
+// acquire acquires n units of the semaphore. If the acquisition has to wait, it is served according to priority (see Condition.WaitWithPriority) and then the fairness policy.
+func (s *CountingSemaphore) acquire(n int, priority int, a Action) *Acquisition {
+	if n < 1 || n > s.max {
+		panic(fmt.Sprintf("cannot acquire %d units of a semaphore with count %d", n, s.max))
 	}
+	acq := &Acquisition{sem: s, n: n, priority: priority, requested: s.sim.Now}
+	defer s.stats.update()
+	if s.available(n) {
+		s.executing += n
+		s.notifyGranted(acq)
 
-	// Schedule this instead of executing immediately to make sure code is only running within the simulation loop.
-	s.sim.Schedule(Event{
-		When:   time.Time{}, // As soon as possible.
-		Action: f,
+		// Schedule this instead of executing immediately to make sure code is only running within the simulation loop.
+		acq.event = s.sim.Immediately(func(sim *Simulation) {
+			acq.event = 0
+			acq.granted(sim, a)
+		})
+		return acq
+	}
+
+	// Too many actions are being executed. Wait for the semaphore to be handed over by Release.
+	acq.waitID = s.readyToExecute.WaitWithPriority(priority, func(sim *Simulation) {
+		acq.granted(sim, a)
 	})
+	acq.queued = true
+	s.waiting[acq.waitID] = acq
+	return acq
 }
 
-// Release releases the semaphore.
+// AcquireWithTimeout is like Acquire, but gives up if the semaphore hasn't been acquired within the duration d. onAcquired is run if the semaphore is acquired in time, otherwise onTimeout is run. Only one of them is ever run.
+func (s *CountingSemaphore) AcquireWithTimeout(d time.Duration, onAcquired, onTimeout Action) *Acquisition {
+	acq := s.Acquire(onAcquired)
+	acq.timer = s.sim.After(d, func(sim *Simulation) {
+		acq.timer = 0
+		if acq.Cancel() {
+			onTimeout(sim)
+		}
+	})
+	return acq
+}
+
+// TryAcquire acquires the semaphore only if it is available right now, without waiting. Returns the acquisition holding the semaphore, which must be released once done using Acquisition.Release, or nil if the semaphore wasn't acquired. Note that, using FairnessFIFO, the semaphore isn't available to TryAcquire while there are actions waiting for it.
+func (s *CountingSemaphore) TryAcquire() *Acquisition {
+	return s.TryAcquireN(1)
+}
+
+// TryAcquireN is like TryAcquire, but acquires n units of the semaphore at once.
+func (s *CountingSemaphore) TryAcquireN(n int) *Acquisition {
+	if n < 1 || !s.available(n) {
+		return nil
+	}
+	s.executing += n
+	acq := &Acquisition{sem: s, n: n, requested: s.sim.Now}
+	s.notifyGranted(acq)
+	s.stats.update()
+	return acq
+}
+
+// available returns whether n units can be acquired right now, without jumping the queue when using FairnessFIFO.
+func (s *CountingSemaphore) available(n int) bool {
+	if s.executing+n > s.max {
+		return false
+	}
+	return s.fairness != FairnessFIFO || s.readyToExecute.Waiting() == 0
+}
+
+// Release releases the semaphore. If actions are waiting for the semaphore, it is handed over to them.
 func (s *CountingSemaphore) Release() {
-	s.executing--
-	if s.executing < s.max {
-		// There is now space for one more action to acquire the semaphore. Signaling.
-		s.readyToExecute.Signal()
+	s.ReleaseN(1)
+}
+
+// ReleaseN releases n units of the semaphore, which were acquired using AcquireN or TryAcquireN. Releasing more units than are held is a misuse (see MisusePolicy), and releases nothing. Panics if n is less than 1.
+//
+// Units can also be released by the Acquisition that holds them, see Acquisition.Release. Units released using ReleaseN are taken from the acquisitions that have held them the longest, so releasing such an acquisition afterwards is a misuse rather than releasing the units twice.
+func (s *CountingSemaphore) ReleaseN(n int) {
+	if n < 1 {
+		panic(fmt.Sprintf("cannot release %d units of a semaphore", n))
+	}
+	if n > s.executing {
+		s.sim.misuse("released %d units of a semaphore with %d units held", n, s.executing)
+		return
+	}
+	s.executing -= n
+	for n > 0 {
+		acq := s.holders[0]
+		taken := min(acq.held, n)
+		acq.held -= taken
+		n -= taken
+		if acq.held == 0 {
+			s.forget(acq)
+		}
+	}
+	s.handOver()
+}
+
+// release releases the units held by acq.
+func (s *CountingSemaphore) release(acq *Acquisition) {
+	s.executing -= acq.held
+	acq.held = 0
+	s.forget(acq)
+	s.handOver()
+}
+
+// forget forgets acq, which no longer holds any units.
+func (s *CountingSemaphore) forget(acq *Acquisition) {
+	if i := slices.Index(s.holders, acq); i >= 0 {
+		s.holders = slices.Delete(s.holders, i, i+1)
+	}
+	s.sim.untrackHeld(acq)
+}
+
+// handOver hands the available units over to waiting actions, according to the fairness policy.
+func (s *CountingSemaphore) handOver() {
+	s.handOverToWaiting()
+	s.stats.update()
+}
+
+func (s *CountingSemaphore) handOverToWaiting() {
+	if s.fairness == FairnessFIFO {
+		for {
+			id, ok := s.readyToExecute.front()
+			if !ok || !s.grant(id) {
+				return
+			}
+		}
+	}
+	if s.readyToExecute.Waiting() == 0 || s.executing >= s.max {
+		return
+	}
+	for id := range s.readyToExecute.ordered() {
+		s.grant(id)
+	}
+}
+
+// grant hands units over to the waiting acquisition with the given ID, if there are enough of them available. Returns false otherwise.
+func (s *CountingSemaphore) grant(id ConditionActionID) bool {
+	acq := s.waiting[id]
+	if s.executing+acq.n > s.max {
+		return false
+	}
+	s.executing += acq.n
+	delete(s.waiting, id)
+	acq.queued = false
+	s.notifyGranted(acq)
+	s.readyToExecute.signalID(id)
+	return true
+}
+
+// notifyGranted records that units have been handed over to acq, and calls the onGranted hook, if any.
+func (s *CountingSemaphore) notifyGranted(acq *Acquisition) {
+	acq.since = s.sim.Now
+	acq.held = acq.n
+	s.holders = append(s.holders, acq)
+	s.sim.trackHeld(acq)
+	s.stats.serve(acq.requested)
+	if s.onGranted != nil {
+		s.onGranted(acq)
+	}
+}
+
+// Acquisition is a pending or completed acquisition of a semaphore or resource, returned by methods such as Acquire and AcquireWithTimeout.
+type Acquisition struct {
+	sem *CountingSemaphore
+
+	// n is the number of units acquired, with the given priority. requested is when the acquisition was made, and since is when the units were handed over. held is the number of units currently held.
+	n         int
+	held      int
+	priority  int
+	requested time.Time
+	since     time.Time
+
+	// event is the event that runs the action of an acquisition that was granted right away, if it hasn't run yet.
+	event EventID
+
+	// waitID is the ID of the action waiting for the semaphore, if queued is set.
+	waitID ConditionActionID
+	queued bool
+
+	// timer is the event that gives up the acquisition, if it was made with a timeout.
+	timer EventID
+
+	// revoked is set if the units have been taken back from the acquisition (see PreemptiveResource). Its action is then never run. onPreempted is run instead.
+	revoked     bool
+	onPreempted func(*Simulation, Preemption)
+}
+
+// Cancel stops waiting for the semaphore. Returns true if the acquisition was cancelled, in which case its action will never run. Returns false if the acquisition was already cancelled, or if the semaphore has already been handed over to it, in which case its action runs (or has run) as usual and the semaphore must be released.
+func (acq *Acquisition) Cancel() bool {
+	cancelled := false
+	switch {
+	case acq.event != 0:
+		if acq.sem.sim.Cancel(acq.event) {
+			// The semaphore was acquired, but never used. Give it back.
+			acq.sem.release(acq)
+			cancelled = true
+		}
+		acq.event = 0
+	case acq.queued:
+		cancelled = acq.sem.readyToExecute.Cancel(acq.waitID)
+		delete(acq.sem.waiting, acq.waitID)
+		acq.queued = false
+		if cancelled {
+			// Actions waiting behind this one might fit now.
+			acq.sem.handOver()
+		}
+	}
+	if cancelled && acq.timer != 0 {
+		acq.sem.sim.Cancel(acq.timer)
+		acq.timer = 0
 	}
+	return cancelled
+}
+
+// Release releases the units held by the acquisition, handing them over to actions waiting for them. An acquisition must be released exactly once, after its action has started running. Releasing an acquisition that doesn't hold any units, for example because it has already been released, was cancelled or was preempted, is a misuse (see MisusePolicy), and releases nothing.
+func (acq *Acquisition) Release() {
+	if acq.held == 0 {
+		acq.sem.sim.misuse("released an acquisition that doesn't hold any units")
+		return
+	}
+	acq.sem.release(acq)
+}
+
+// granted runs the action of the acquisition, which now holds the semaphore.
+func (acq *Acquisition) granted(sim *Simulation, a Action) {
+	if acq.timer != 0 {
+		sim.Cancel(acq.timer)
+		acq.timer = 0
+	}
+	if acq.revoked {
+		return
+	}
+	a(sim)
 }
 
 // BinarySemaphore is a semaphore that can be used to synchronize actions. It is a counting semaphore with a maximum of 1. Since a simulation can only run one action at a time, this library does not implement any mutex[1]
@@ -178,19 +628,169 @@ type BinarySemaphore struct {
 }
 
 // NewBinarySemaphore creates a new binary semaphore.
-func NewBinarySemaphore(sim *Simulation) *BinarySemaphore {
+func NewBinarySemaphore(sim *Simulation, opts ...ResourceOption) *BinarySemaphore {
 	return &BinarySemaphore{
 		sim:       sim,
-		semaphore: NewCountingSemaphore(sim, 1),
+		semaphore: newCountingSemaphore(sim, 1, newResourceOptions("BinarySemaphore", opts)),
 	}
 }
 
+// Stats returns statistics about how the semaphore has been used. See CountingSemaphore.Stats.
+func (s *BinarySemaphore) Stats() ResourceStats {
+	return s.semaphore.Stats()
+}
+
 // Acquire acquires the semaphore. If the semaphore is already acquired, the action will be scheduled to run when the semaphore is released. Do not forget to call Release() when the action is done (unless you want to hold the semaphore for longer).
-func (s *BinarySemaphore) Acquire(a Action) {
-	s.semaphore.Acquire(a)
+//
+// The returned Acquisition can be used to stop waiting for the semaphore.
+func (s *BinarySemaphore) Acquire(a Action) *Acquisition {
+	return s.semaphore.Acquire(a)
+}
+
+// AcquireWithTimeout is like Acquire, but gives up if the semaphore hasn't been acquired within the duration d. See CountingSemaphore.AcquireWithTimeout.
+func (s *BinarySemaphore) AcquireWithTimeout(d time.Duration, onAcquired, onTimeout Action) *Acquisition {
+	return s.semaphore.AcquireWithTimeout(d, onAcquired, onTimeout)
+}
+
+// TryAcquire acquires the semaphore only if it is available right now, without waiting. See CountingSemaphore.TryAcquire.
+func (s *BinarySemaphore) TryAcquire() *Acquisition {
+	return s.semaphore.TryAcquire()
 }
 
 // Release releases the semaphore.
 func (s *BinarySemaphore) Release() {
 	s.semaphore.Release()
 }
+
+// Latch is a countdown latch. Actions waiting for the latch are woken up once it has been counted down to zero, and from then on the latch stays open. It is useful to wait for a fixed number of things to happen, such as parallel sub-tasks to finish.
+type Latch struct {
+	count int
+	open  *Condition
+}
+
+// NewLatch creates a new latch that opens once it has been counted down count times.
+func NewLatch(sim *Simulation, count int) *Latch {
+	if count < 0 {
+		panic("count must not be negative")
+	}
+	return &Latch{
+		count: count,
+		open:  NewCondition(sim),
+	}
+}
+
+// CountDown counts the latch down by one. If the count reaches zero, all waiting actions are woken up. Counting down an open latch does nothing.
+func (l *Latch) CountDown() {
+	if l.count == 0 {
+		return
+	}
+	l.count--
+	if l.count == 0 {
+		l.open.Broadcast()
+	}
+}
+
+// Count returns the number of times the latch has to be counted down before it opens.
+func (l *Latch) Count() int {
+	return l.count
+}
+
+// Wait makes an action wait for the latch to open. If the latch is already open, the action is run as soon as possible. It returns an ID that can be used to cancel the wait.
+func (l *Latch) Wait(a Action) ConditionActionID {
+	id := l.open.Wait(a)
+	if l.count == 0 {
+		l.open.Broadcast()
+	}
+	return id
+}
+
+// Cancel cancels an action waiting for the latch. See Condition.Cancel.
+func (l *Latch) Cancel(id ConditionActionID) bool {
+	return l.open.Cancel(id)
+}
+
+// Barrier is a cyclic barrier. Actions waiting for the barrier are woken up together once a number of them, called parties, are waiting. The barrier then starts over, so it can be reused for the next round.
+type Barrier struct {
+	parties int
+	arrived int
+	tripped *Condition
+}
+
+// NewBarrier creates a new barrier for parties actions.
+func NewBarrier(sim *Simulation, parties int) *Barrier {
+	if parties < 1 {
+		panic("parties must be at least 1")
+	}
+	return &Barrier{
+		parties: parties,
+		tripped: NewCondition(sim),
+	}
+}
+
+// Wait makes an action wait at the barrier. If it is the last of the parties to arrive, all of them are woken up. It returns an ID that can be used to cancel the wait.
+func (b *Barrier) Wait(a Action) ConditionActionID {
+	id := b.tripped.Wait(a)
+	b.arrived++
+	if b.arrived == b.parties {
+		b.arrived = 0
+		b.tripped.Broadcast()
+	}
+	return id
+}
+
+// Waiting returns the number of actions waiting at the barrier.
+func (b *Barrier) Waiting() int {
+	return b.arrived
+}
+
+// Cancel cancels an action waiting at the barrier, which then needs one more party to arrive before it trips. See Condition.Cancel.
+func (b *Barrier) Cancel(id ConditionActionID) bool {
+	if !b.tripped.Cancel(id) {
+		return false
+	}
+	b.arrived--
+	return true
+}
+
+// WaitGroup waits for a collection of tasks to finish, similarly to sync.WaitGroup. Add is called with the number of tasks to wait for, and Done is called when each of them finishes. Actions waiting for the group are woken up once the counter reaches zero.
+type WaitGroup struct {
+	counter int
+	zero    *Condition
+}
+
+// NewWaitGroup creates a new wait group with a counter of zero.
+func NewWaitGroup(sim *Simulation) *WaitGroup {
+	return &WaitGroup{
+		zero: NewCondition(sim),
+	}
+}
+
+// Add adds delta, which may be negative, to the counter. If the counter reaches zero, all waiting actions are woken up. Panics if the counter becomes negative.
+func (wg *WaitGroup) Add(delta int) {
+	wg.counter += delta
+	if wg.counter < 0 {
+		panic("negative WaitGroup counter")
+	}
+	if wg.counter == 0 {
+		wg.zero.Broadcast()
+	}
+}
+
+// Done decrements the counter by one.
+func (wg *WaitGroup) Done() {
+	wg.Add(-1)
+}
+
+// Wait makes an action wait for the counter to reach zero. If it already is zero, the action is run as soon as possible. It returns an ID that can be used to cancel the wait.
+func (wg *WaitGroup) Wait(a Action) ConditionActionID {
+	id := wg.zero.Wait(a)
+	if wg.counter == 0 {
+		wg.zero.Broadcast()
+	}
+	return id
+}
+
+// Cancel cancels an action waiting for the group. See Condition.Cancel.
+func (wg *WaitGroup) Cancel(id ConditionActionID) bool {
+	return wg.zero.Cancel(id)
+}
@@ -2,6 +2,7 @@ package steps
 
 import (
 	"fmt"
+	"slices"
 	"testing"
 	"time"
 )
@@ -64,6 +65,123 @@ func TestConditionBroadcast(t *testing.T) {
 	}
 }
 
+func TestConditionPriority(t *testing.T) {
+	s := NewSimulation()
+	c := NewCondition(s)
+
+	var order []string
+	record := func(name string) Action {
+		return func(*Simulation) { order = append(order, name) }
+	}
+	c.WaitWithPriority(1, record("low 1"))
+	c.Wait(record("default"))
+	c.WaitWithPriority(1, record("low 2"))
+	c.WaitWithPriority(-1, record("high"))
+
+	// Signal wakes up the waiter with the highest priority.
+	c.Signal()
+	s.RunUntilDone()
+	if expected := []string{"high"}; fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("expected order %v, got %v", expected, order)
+	}
+
+	c.Broadcast()
+	s.RunUntilDone()
+	if expected := []string{"high", "default", "low 1", "low 2"}; fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("expected order %v, got %v", expected, order)
+	}
+}
+
+func TestConditionOrderWithCancels(t *testing.T) {
+	sim := NewSimulation(WithSeed(42))
+	r := sim.NewRand()
+	c := NewCondition(sim)
+
+	// waiting is the expected order of the waiting actions, kept sorted by priority and then ID.
+	type waiter struct {
+		id       ConditionActionID
+		priority int
+	}
+	var waiting []waiter
+	for range 1000 {
+		switch r.IntN(3) {
+		case 0, 1:
+			priority := r.IntN(5) - 2
+			id := c.WaitWithPriority(priority, func(*Simulation) {})
+			i := slices.IndexFunc(waiting, func(w waiter) bool { return w.priority > priority })
+			if i < 0 {
+				i = len(waiting)
+			}
+			waiting = slices.Insert(waiting, i, waiter{id, priority})
+		case 2:
+			if len(waiting) == 0 {
+				continue
+			}
+			i := r.IntN(len(waiting))
+			if !c.Cancel(waiting[i].id) {
+				t.Fatalf("expected to cancel %d", waiting[i].id)
+			}
+			waiting = slices.Delete(waiting, i, i+1)
+		}
+
+		var expected []ConditionActionID
+		for _, w := range waiting {
+			expected = append(expected, w.id)
+		}
+		if got := slices.Collect(c.ordered()); !slices.Equal(got, expected) {
+			t.Fatalf("expected order %v, got %v", expected, got)
+		}
+	}
+}
+
+func TestConditionBroadcastSkipsCancelledActions(t *testing.T) {
+	sim := NewSimulation()
+	c := NewCondition(sim)
+
+	var order []string
+	var later ConditionActionID
+	c.WaitUntil(func() bool {
+		c.Cancel(later)
+		return true
+	}, func(*Simulation) { order = append(order, "first") })
+	later = c.WaitUntil(func() bool {
+		t.Error("expected the predicate of a cancelled action to never be called")
+		return true
+	}, func(*Simulation) { order = append(order, "cancelled") })
+	c.Wait(func(*Simulation) { order = append(order, "last") })
+	c.Broadcast()
+	c.Wait(func(*Simulation) { order = append(order, "too late") })
+	sim.RunUntilDone()
+
+	if expected := []string{"first", "last"}; fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("expected order %v, got %v", expected, order)
+	}
+	if c.Waiting() != 1 {
+		t.Errorf("expected the action waiting after the broadcast to keep waiting, got %d waiting", c.Waiting())
+	}
+}
+
+func TestConditionWakeUpsAreScheduledWithPriority(t *testing.T) {
+	s := NewSimulation()
+	c1 := NewCondition(s)
+	c2 := NewCondition(s)
+
+	var order []string
+	record := func(name string) Action {
+		return func(*Simulation) { order = append(order, name) }
+	}
+	c1.WaitWithPriority(1, record("arrival"))
+	c2.WaitWithPriority(-1, record("departure"))
+
+	c1.Broadcast()
+	c2.Broadcast()
+	s.RunUntilDone()
+
+	if expected := []string{"departure", "arrival"}; fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("expected order %v, got %v", expected, order)
+	}
+}
+
 type testAction struct {
 	executed bool
 }
@@ -146,6 +264,83 @@ func ExampleCondition() {
 	// 0001-01-01 00:00:09 +0000 UTC Processing...
 }
 
+// ExampleLatch demonstrates how to use a Latch to wait for three (3) parallel sub-tasks to finish before continuing.
+func ExampleLatch() {
+	sim := NewSimulation()
+	subTasks := NewLatch(sim, 3)
+
+	for i := range 3 {
+		sim.After(time.Duration(i+1)*time.Second, func(sim *Simulation) {
+			fmt.Println(sim.Now, "Sub-task", i, "done")
+			subTasks.CountDown()
+		})
+	}
+	subTasks.Wait(func(sim *Simulation) {
+		fmt.Println(sim.Now, "All sub-tasks done")
+	})
+	sim.RunUntilDone()
+
+	// Output:
+	// 0001-01-01 00:00:01 +0000 UTC Sub-task 0 done
+	// 0001-01-01 00:00:02 +0000 UTC Sub-task 1 done
+	// 0001-01-01 00:00:03 +0000 UTC Sub-task 2 done
+	// 0001-01-01 00:00:03 +0000 UTC All sub-tasks done
+}
+
+// ExampleBarrier demonstrates how to use a Barrier to make two (2) workers, working at different speeds, start every round together.
+func ExampleBarrier() {
+	sim := NewSimulation()
+	round := NewBarrier(sim, 2)
+
+	for worker, speed := range []time.Duration{time.Second, 3 * time.Second} {
+		var work func(sim *Simulation)
+		rounds := 0
+		work = func(sim *Simulation) {
+			fmt.Println(sim.Now, "Worker", worker, "starting round", rounds)
+			rounds++
+			if rounds == 2 {
+				return
+			}
+			sim.After(speed, func(*Simulation) { round.Wait(work) })
+		}
+		round.Wait(work)
+	}
+	sim.RunUntilDone()
+
+	// Output:
+	// 0001-01-01 00:00:00 +0000 UTC Worker 0 starting round 0
+	// 0001-01-01 00:00:00 +0000 UTC Worker 1 starting round 0
+	// 0001-01-01 00:00:03 +0000 UTC Worker 0 starting round 1
+	// 0001-01-01 00:00:03 +0000 UTC Worker 1 starting round 1
+}
+
+// ExampleWaitGroup demonstrates how to use a WaitGroup to wait for a number of tasks that isn't known up front.
+func ExampleWaitGroup() {
+	sim := NewSimulation()
+	tasks := NewWaitGroup(sim)
+
+	var spawn func(depth int)
+	spawn = func(depth int) {
+		tasks.Add(1)
+		sim.After(time.Second, func(sim *Simulation) {
+			if depth < 2 {
+				// Every task spawns two more.
+				spawn(depth + 1)
+				spawn(depth + 1)
+			}
+			tasks.Done()
+		})
+	}
+	spawn(0)
+	tasks.Wait(func(sim *Simulation) {
+		fmt.Println(sim.Now, "All tasks done")
+	})
+	sim.RunUntilDone()
+
+	// Output:
+	// 0001-01-01 00:00:03 +0000 UTC All tasks done
+}
+
 // ExampleBinarySemaphore demonstrates how to use the BinarySemaphore to synchronize actions. It simulates processing ten (10) items, one (1) at a time.
 func ExampleBinarySemaphore() {
 	sim := NewSimulation()
@@ -232,3 +427,467 @@ func ExampleCountingSemaphore() {
 	// 0001-01-01 00:00:30 +0000 UTC Done processing item 8
 	// 0001-01-01 00:00:40 +0000 UTC Done processing item 9
 }
+
+// ExampleCountingSemaphore_AcquireWithTimeout simulates customers reneging if they have to wait for more than five (5) seconds to be served.
+func ExampleCountingSemaphore_AcquireWithTimeout() {
+	sim := NewSimulation()
+	sem := NewCountingSemaphore(sim, 1)
+
+	timeToServe := 3 * time.Second
+	for i := range 4 {
+		sem.AcquireWithTimeout(5*time.Second, func(sim *Simulation) {
+			fmt.Println(sim.Now, "Serving customer", i)
+			sim.After(timeToServe, func(sim *Simulation) {
+				sem.Release()
+			})
+		}, func(sim *Simulation) {
+			fmt.Println(sim.Now, "Customer", i, "reneged")
+		})
+	}
+	sim.RunUntilDone()
+
+	// Output:
+	// 0001-01-01 00:00:00 +0000 UTC Serving customer 0
+	// 0001-01-01 00:00:03 +0000 UTC Serving customer 1
+	// 0001-01-01 00:00:05 +0000 UTC Customer 2 reneged
+	// 0001-01-01 00:00:05 +0000 UTC Customer 3 reneged
+}
+
+func TestCountingSemaphoreLateArrivalsDoNotExceedCount(t *testing.T) {
+	sim := NewSimulation()
+	sem := NewCountingSemaphore(sim, 2)
+
+	running, maxRunning := 0, 0
+	for i := range 20 {
+		// Arrivals keep coming while others are waiting for the semaphore.
+		sim.After(time.Duration(i)*time.Second, func(*Simulation) {
+			sem.Acquire(func(sim *Simulation) {
+				running++
+				maxRunning = max(maxRunning, running)
+				sim.After(5*time.Second, func(*Simulation) {
+					running--
+					sem.Release()
+				})
+			})
+		})
+	}
+	sim.RunUntilDone()
+
+	if maxRunning != 2 {
+		t.Errorf("expected at most 2 running, got %d", maxRunning)
+	}
+	if sem.executing != 0 {
+		t.Errorf("expected nothing to be executing, got %d", sem.executing)
+	}
+}
+
+func TestAcquisitionCancel(t *testing.T) {
+	sim := NewSimulation()
+	sem := NewBinarySemaphore(sim)
+
+	var order []string
+	record := func(name string) Action {
+		return func(*Simulation) { order = append(order, name) }
+	}
+	first := sem.Acquire(record("first"))
+	waiting := sem.Acquire(record("waiting"))
+	sem.Acquire(record("last"))
+
+	if !waiting.Cancel() {
+		t.Error("expected a waiting acquisition to be cancelled")
+	}
+	// Cancelling an acquisition that was granted, but hasn't run yet, hands the semaphore to the next in line.
+	if !first.Cancel() {
+		t.Error("expected a granted acquisition to be cancelled before it ran")
+	}
+	if first.Cancel() || waiting.Cancel() {
+		t.Error("expected cancelling twice to return false")
+	}
+	sim.RunUntilDone()
+
+	if expected := []string{"last"}; fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, order)
+	}
+	if sem.TryAcquire() != nil {
+		t.Error("expected the semaphore to still be held by the last acquisition")
+	}
+}
+
+func TestAcquisitionCancelAfterHandOver(t *testing.T) {
+	sim := NewSimulation()
+	sem := NewBinarySemaphore(sim)
+
+	acquired := false
+	sem.Acquire(func(sim *Simulation) { sim.After(time.Second, func(*Simulation) { sem.Release() }) })
+	acq := sem.Acquire(func(*Simulation) { acquired = true })
+	sim.RunFor(time.Second)
+
+	// The semaphore has been handed over, but the action hasn't run yet.
+	if acq.Cancel() {
+		t.Error("expected an acquisition that has been handed the semaphore to not be cancelled")
+	}
+	sim.RunUntilDone()
+	if !acquired {
+		t.Error("expected the action to run")
+	}
+}
+
+func TestAcquireWithTimeoutAcquiredInTime(t *testing.T) {
+	sim := NewSimulation()
+	sem := NewCountingSemaphore(sim, 1)
+
+	var acquiredAt time.Time
+	timedOut := false
+	sem.Acquire(func(sim *Simulation) { sim.After(time.Second, func(*Simulation) { sem.Release() }) })
+	sem.AcquireWithTimeout(time.Hour, func(sim *Simulation) { acquiredAt = sim.Now }, func(*Simulation) { timedOut = true })
+	sim.RunUntilDone()
+
+	if timedOut {
+		t.Error("expected the acquisition to not time out")
+	}
+	if expected := (time.Time{}).Add(time.Second); !acquiredAt.Equal(expected) || !sim.Now.Equal(expected) {
+		t.Errorf("expected to acquire at %s without waiting for the timer, got %s and the clock at %s", expected, acquiredAt, sim.Now)
+	}
+}
+
+func TestTryAcquire(t *testing.T) {
+	sim := NewSimulation()
+	sem := NewCountingSemaphore(sim, 2)
+
+	if sem.TryAcquire() == nil || sem.TryAcquire() == nil {
+		t.Error("expected the free semaphore to be acquired")
+	}
+	if sem.TryAcquire() != nil {
+		t.Error("expected the full semaphore to not be acquired")
+	}
+
+	acquired := false
+	sem.Acquire(func(*Simulation) { acquired = true })
+	sem.Release()
+	if sem.TryAcquire() != nil {
+		t.Error("expected the released semaphore to be handed over to the waiting action")
+	}
+	sim.RunUntilDone()
+	if !acquired {
+		t.Error("expected the waiting action to acquire the semaphore")
+	}
+}
+
+// ExampleCountingSemaphore_AcquireN simulates jobs that need a number of CPU cores each, on a machine with four (4) cores.
+func ExampleCountingSemaphore_AcquireN() {
+	sim := NewSimulation()
+	cores := NewCountingSemaphore(sim, 4)
+
+	for i, needed := range []int{2, 3, 1} {
+		cores.AcquireN(needed, func(sim *Simulation) {
+			fmt.Println(sim.Now, "Job", i, "running on", needed, "cores")
+			sim.After(10*time.Second, func(*Simulation) {
+				cores.ReleaseN(needed)
+			})
+		})
+	}
+	sim.RunUntilDone()
+
+	// Output:
+	// 0001-01-01 00:00:00 +0000 UTC Job 0 running on 2 cores
+	// 0001-01-01 00:00:10 +0000 UTC Job 1 running on 3 cores
+	// 0001-01-01 00:00:10 +0000 UTC Job 2 running on 1 cores
+}
+
+func TestCountingSemaphoreFairness(t *testing.T) {
+	for _, test := range []struct {
+		fairness Fairness
+		expected []string
+	}{
+		{FairnessFIFO, []string{"0s big", "10s big", "10s small"}},
+		{FairnessFirstFit, []string{"0s big", "0s small", "10s big"}},
+	} {
+		sim := NewSimulation()
+		sem := NewCountingSemaphore(sim, 4, WithFairness(test.fairness))
+
+		var order []string
+		use := func(name string, n int) {
+			sem.AcquireN(n, func(sim *Simulation) {
+				order = append(order, fmt.Sprint(sim.Now.Sub(time.Time{}), " ", name))
+				sim.After(10*time.Second, func(*Simulation) { sem.ReleaseN(n) })
+			})
+		}
+		use("big", 3)
+		use("big", 3)
+		use("small", 1)
+		sim.RunUntilDone()
+
+		if fmt.Sprint(order) != fmt.Sprint(test.expected) {
+			t.Errorf("fairness %d: expected %v, got %v", test.fairness, test.expected, order)
+		}
+	}
+}
+
+func TestCountingSemaphoreCancelUnblocksWaiters(t *testing.T) {
+	sim := NewSimulation()
+	sem := NewCountingSemaphore(sim, 4)
+
+	if sem.TryAcquireN(2) == nil {
+		t.Fatal("expected to acquire 2 units")
+	}
+	big := sem.AcquireN(4, func(*Simulation) { t.Error("expected the cancelled acquisition to never run") })
+	acquired := false
+	sem.AcquireN(2, func(*Simulation) { acquired = true })
+
+	// The small acquisition is blocked behind the big one until it is cancelled.
+	sim.RunUntilDone()
+	if acquired {
+		t.Error("expected the small acquisition to wait behind the big one")
+	}
+	big.Cancel()
+	sim.RunUntilDone()
+	if !acquired {
+		t.Error("expected the small acquisition to run once the big one was cancelled")
+	}
+}
+
+func TestCountingSemaphoreAcquireTooMany(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	NewCountingSemaphore(NewSimulation(), 2).AcquireN(3, func(*Simulation) {})
+}
+
+func TestCountingSemaphoreReleaseNonPositive(t *testing.T) {
+	sem := NewCountingSemaphore(NewSimulation(), 2)
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected a panic")
+		}
+		if sem.TryAcquireN(2) == nil {
+			t.Error("expected no units to be held")
+		}
+	}()
+	sem.ReleaseN(-1)
+}
+
+func TestRequestsOfDifferentResourcesAreCancelled(t *testing.T) {
+	sim := NewSimulation()
+	lock := NewRWLock(sim)
+	lock.Lock(func(*Simulation) {})
+
+	fail := func(*Simulation) { t.Error("expected the request to be cancelled") }
+	requests := []*Request{
+		NewStore[int](sim, 0).Get(func(sim *Simulation, _ int) { fail(sim) }),
+		NewContainer(sim, 10, 0).Get(1, fail),
+		NewMailbox[int](sim, 0).Receive(func(sim *Simulation, _ int) { fail(sim) }),
+		lock.RLock(fail),
+	}
+	for i, req := range requests {
+		if !req.Cancel() {
+			t.Errorf("request %d: expected to be cancelled", i)
+		}
+		if req.Cancel() {
+			t.Errorf("request %d: expected a second cancel to fail", i)
+		}
+	}
+	sim.RunUntilDone()
+
+	if served := NewStore[int](sim, 0).Put(1, nil); served.Cancel() {
+		t.Error("expected a served put to not be cancellable")
+	}
+}
+
+func TestResourceOptionsThatDoNotApplyPanic(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		create func(sim *Simulation)
+	}{
+		{"overflow for a store", func(sim *Simulation) { NewStore[int](sim, 3, WithOverflow(OverflowDropOldest)) }},
+		{"preference for a mailbox", func(sim *Simulation) { NewMailbox[int](sim, 3, WithRWPreference(PreferReaders)) }},
+		{"fairness for a lock", func(sim *Simulation) { NewRWLock(sim, WithFairness(FairnessFirstFit)) }},
+		{"overflow for a semaphore", func(sim *Simulation) { NewCountingSemaphore(sim, 2, WithOverflow(OverflowDropNewest)) }},
+		{"fairness for a binary semaphore", func(sim *Simulation) { NewBinarySemaphore(sim, WithFairness(FairnessFirstFit)) }},
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("%s: expected a panic", test.name)
+				}
+			}()
+			test.create(NewSimulation())
+		}()
+	}
+
+	// Options that apply, and WithStats, are accepted.
+	sim := NewSimulation()
+	NewMailbox[int](sim, 3, WithOverflow(OverflowDropOldest), WithStats())
+	NewRWLock(sim, WithRWPreference(PreferReaders), WithStats())
+	NewContainer(sim, 10, 0, WithFairness(FairnessFirstFit), WithStats())
+	NewPreemptiveResource(sim, 2, WithFairness(FairnessFirstFit), WithStats())
+	NewStore[int](sim, 3, WithStats())
+}
+
+func TestCountingSemaphoreNeverOverAllocates(t *testing.T) {
+	for _, fairness := range []Fairness{FairnessFIFO, FairnessFirstFit} {
+		sim := NewSimulation(WithSeed(uint64(fairness)))
+		const count = 8
+		sem := NewCountingSemaphore(sim, count, WithFairness(fairness))
+
+		inUse, maxInUse, completed, cancelled := 0, 0, 0, 0
+		const jobs = 1000
+		for range jobs {
+			n := 1 + sim.Rand.IntN(count)
+			arrival := time.Duration(sim.Rand.IntN(1000)) * time.Second
+			hold := time.Duration(1+sim.Rand.IntN(20)) * time.Second
+			patience := time.Duration(1+sim.Rand.IntN(200)) * time.Second
+			sim.After(arrival, func(sim *Simulation) {
+				acq := sem.AcquireN(n, func(sim *Simulation) {
+					inUse += n
+					maxInUse = max(maxInUse, inUse)
+					sim.After(hold, func(*Simulation) {
+						inUse -= n
+						completed++
+						sem.ReleaseN(n)
+					})
+				})
+				sim.After(patience, func(*Simulation) {
+					if acq.Cancel() {
+						cancelled++
+					}
+				})
+			})
+		}
+		sim.RunUntilDone()
+
+		if maxInUse > count {
+			t.Errorf("fairness %d: expected at most %d units in use, got %d", fairness, count, maxInUse)
+		}
+		if completed+cancelled != jobs {
+			t.Errorf("fairness %d: expected %d jobs to complete or be cancelled, got %d and %d", fairness, jobs, completed, cancelled)
+		}
+		if sem.executing != 0 || sem.readyToExecute.Waiting() != 0 || len(sem.waiting) != 0 {
+			t.Errorf("fairness %d: expected all units to be released, got %d in use and %d waiting", fairness, sem.executing, sem.readyToExecute.Waiting())
+		}
+	}
+}
+
+// ExampleCondition_WaitUntil simulates customers waiting for a bank balance to be high enough for their withdrawals, where every deposit is broadcast.
+func ExampleCondition_WaitUntil() {
+	sim := NewSimulation()
+	deposited := NewCondition(sim)
+	balance := 0
+
+	for _, amount := range []int{50, 20, 30} {
+		deposited.WaitUntil(func() bool { return balance >= amount }, func(sim *Simulation) {
+			balance -= amount
+			fmt.Println(sim.Now, "Withdrew", amount, "leaving", balance)
+			// The balance changed, so others might be able to withdraw now.
+			deposited.Broadcast()
+		})
+	}
+	for i, amount := range []int{25, 40, 35} {
+		sim.After(time.Duration(i+1)*time.Second, func(*Simulation) {
+			balance += amount
+			deposited.Broadcast()
+		})
+	}
+	sim.RunUntilDone()
+
+	// Output:
+	// 0001-01-01 00:00:01 +0000 UTC Withdrew 20 leaving 5
+	// 0001-01-01 00:00:02 +0000 UTC Withdrew 30 leaving 15
+	// 0001-01-01 00:00:03 +0000 UTC Withdrew 50 leaving 0
+}
+
+func TestConditionWaitUntilKeepsPlaceInLine(t *testing.T) {
+	s := NewSimulation()
+	c := NewCondition(s)
+
+	ready := map[string]bool{}
+	var order []string
+	wait := func(name string) ConditionActionID {
+		return c.WaitUntil(func() bool { return ready[name] }, func(*Simulation) { order = append(order, name) })
+	}
+	wait("a")
+	wait("b")
+	c.Wait(func(*Simulation) { order = append(order, "plain") })
+	cancelled := wait("cancelled")
+
+	// Signal wakes up the first action whose predicate is true, skipping the others.
+	ready["b"] = true
+	c.Signal()
+	s.RunUntilDone()
+	if expected := []string{"b"}; fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, order)
+	}
+
+	// a is still first in line once its predicate is true.
+	ready["a"] = true
+	c.Signal()
+	s.RunUntilDone()
+	if expected := []string{"b", "a"}; fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, order)
+	}
+
+	c.Cancel(cancelled)
+	if c.predicates != 0 {
+		t.Errorf("expected no predicates to be left, got %d", c.predicates)
+	}
+	c.Broadcast()
+	s.RunUntilDone()
+	if expected := []string{"b", "a", "plain"}; fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, order)
+	}
+}
+
+func TestConditionSignalWithoutTruePredicate(t *testing.T) {
+	s := NewSimulation()
+	c := NewCondition(s)
+	a := &testAction{}
+	c.WaitUntil(func() bool { return false }, a.Execute)
+	c.Signal()
+	c.Broadcast()
+	s.RunUntilDone()
+
+	if a.executed || c.Waiting() != 1 {
+		t.Error("expected the action to keep waiting")
+	}
+}
+
+func TestLatchAlreadyOpen(t *testing.T) {
+	s := NewSimulation()
+	l := NewLatch(s, 1)
+	l.CountDown()
+	l.CountDown()
+
+	a := &testAction{}
+	l.Wait(a.Execute)
+	s.RunUntilDone()
+	if !a.executed || l.Count() != 0 {
+		t.Error("expected waiting for an open latch to run the action")
+	}
+}
+
+func TestBarrierCancel(t *testing.T) {
+	s := NewSimulation()
+	b := NewBarrier(s, 2)
+
+	a1 := &testAction{}
+	a2 := &testAction{}
+	cancelled := b.Wait(a1.Execute)
+	if !b.Cancel(cancelled) || b.Waiting() != 0 {
+		t.Error("expected the wait to be cancelled")
+	}
+	b.Wait(a2.Execute)
+	s.RunUntilDone()
+	if a1.executed || a2.executed {
+		t.Error("expected the barrier to wait for a second party")
+	}
+}
+
+func TestWaitGroupNegativeCounterPanics(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	NewWaitGroup(NewSimulation()).Done()
+}
@@ -0,0 +1,147 @@
+package steps
+
+import (
+	"context"
+	"time"
+)
+
+// contextCheckInterval is how many events RunContext processes between checking whether its context has been cancelled.
+const contextCheckInterval = 1024
+
+// StopReason is the reason a run of a simulation stopped.
+type StopReason int
+
+const (
+	// StopQueueEmpty means that there were no more events to process.
+	StopQueueEmpty StopReason = iota
+
+	// StopHorizonReached means that the next event is after the time the simulation was run until, or after the horizon of the simulation (see WithHorizon).
+	StopHorizonReached
+
+	// StopStopped means that Simulation.Stop was called.
+	StopStopped
+
+	// StopBudgetExhausted means that the event budget of the simulation was used up (see WithEventBudget).
+	StopBudgetExhausted
+
+	// StopCancelled means that the context passed to Simulation.RunContext was cancelled.
+	StopCancelled
+)
+
+// String returns a string representation of the stop reason.
+func (r StopReason) String() string {
+	switch r {
+	case StopQueueEmpty:
+		return "queue empty"
+	case StopHorizonReached:
+		return "horizon reached"
+	case StopStopped:
+		return "stopped"
+	case StopBudgetExhausted:
+		return "event budget exhausted"
+	case StopCancelled:
+		return "context cancelled"
+	default:
+		return "unknown"
+	}
+}
+
+// Stop makes the currently running run (RunUntil, RunUntilDone, RunContext etc.) return StopStopped once the current event has been processed. It is meant to be called from within an Action. Calling it outside a run has no effect, since every run starts out as not stopped.
+func (s *Simulation) Stop() {
+	s.stopped = true
+}
+
+// RunUntil runs the simulation until the given time or there are no more events to process. It returns the reason it stopped.
+func (s *Simulation) RunUntil(until time.Time) StopReason {
+	reason, _ := s.run(runLimits{until: until, hasUntil: true})
+	return reason
+}
+
+// RunUntilDone runs the simulation until there are no more events to process. It returns the reason it stopped.
+func (s *Simulation) RunUntilDone() StopReason {
+	reason, _ := s.run(runLimits{})
+	return reason
+}
+
+// AdvanceTo is like RunUntil, but also moves the clock to t once all events up to t have been processed, so that Now equals t afterwards even if the last event was earlier. This is useful for time-weighted statistics and periodic reporting. It returns the reason it stopped.
+//
+// The clock is never moved past the horizon of the simulation (see WithHorizon), nor if the run stopped for any other reason than StopQueueEmpty or StopHorizonReached.
+func (s *Simulation) AdvanceTo(t time.Time) StopReason {
+	reason := s.RunUntil(t)
+	if reason != StopQueueEmpty && reason != StopHorizonReached {
+		return reason
+	}
+	if s.hasHorizon && s.horizon.Before(t) {
+		t = s.horizon
+	}
+	if t.After(s.Now) {
+		s.Now = t
+	}
+	return reason
+}
+
+// RunContext is like RunUntilDone, but also stops with StopCancelled if ctx is cancelled. The context is checked periodically, not before every event.
+func (s *Simulation) RunContext(ctx context.Context) StopReason {
+	reason, _ := s.run(runLimits{ctx: ctx})
+	return reason
+}
+
+// RunFor runs the simulation for the duration d, starting from the current time, or until there are no more events to process. It returns the number of events processed. Like RunUntil, it leaves the clock at the last processed event; use AdvanceTo to move it to the end of the duration.
+func (s *Simulation) RunFor(d time.Duration) int {
+	_, processed := s.run(runLimits{until: s.Now.Add(d), hasUntil: true})
+	return processed
+}
+
+// RunSteps processes at most n events. It returns the number of events processed, which is less than n if the simulation ran out of events (or was stopped) before that.
+func (s *Simulation) RunSteps(n int) int {
+	_, processed := s.run(runLimits{steps: n, hasSteps: true})
+	return processed
+}
+
+// RunWhile runs the simulation as long as pred returns true. The predicate is called before every event. It returns the number of events processed.
+func (s *Simulation) RunWhile(pred func(*Simulation) bool) int {
+	_, processed := s.run(runLimits{while: pred})
+	return processed
+}
+
+// runLimits limits a run of a simulation, in addition to the horizon and event budget of the simulation. The zero value means no limits.
+type runLimits struct {
+	// ctx stops the run when cancelled, if not nil.
+	ctx context.Context
+
+	// until stops the run before any event after it, if hasUntil is set.
+	until    time.Time
+	hasUntil bool
+
+	// steps is the maximum number of events to process, if hasSteps is set.
+	steps    int
+	hasSteps bool
+
+	// while stops the run when it returns false, if not nil.
+	while func(*Simulation) bool
+}
+
+// run processes events until one of the limits is reached, the simulation is stopped, or it cannot process any more events. It returns the reason it stopped and the number of events processed. Reaching the steps or while limits is reported as StopStopped, since it is the caller that stopped the run.
+func (s *Simulation) run(l runLimits) (StopReason, int) {
+	s.stopped = false
+	processed := 0
+	for {
+		if l.ctx != nil && processed%contextCheckInterval == 0 && l.ctx.Err() != nil {
+			return StopCancelled, processed
+		}
+		if l.hasSteps && processed >= l.steps {
+			return StopStopped, processed
+		}
+		if reason, blocked := s.blocked(l.until, l.hasUntil); blocked {
+			return reason, processed
+		}
+		if l.while != nil && !l.while(s) {
+			return StopStopped, processed
+		}
+		s.process()
+		processed++
+		if s.stopped {
+			return StopStopped, processed
+		}
+	}
+}
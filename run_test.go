@@ -0,0 +1,295 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// ExampleSimulation_Stop shows how to stop a simulation that would otherwise run forever from within an action.
+func ExampleSimulation_Stop() {
+	sim := NewSimulation()
+
+	ticks := 0
+	Ticker(sim, sim.Now, time.Second, func(s *Simulation) {
+		ticks++
+		if ticks == 3 {
+			s.Stop()
+		}
+	})
+
+	reason := sim.RunUntilDone()
+	fmt.Println("Stopped after", ticks, "ticks:", reason)
+
+	// Output:
+	// Stopped after 3 ticks: stopped
+}
+
+func TestRunStopReasons(t *testing.T) {
+	t.Run("Queue empty", func(t *testing.T) {
+		sim := NewSimulation()
+		sim.After(time.Second, func(*Simulation) {})
+
+		if reason := sim.RunUntilDone(); reason != StopQueueEmpty {
+			t.Errorf("expected %s, got %s", StopQueueEmpty, reason)
+		}
+		if reason := sim.RunUntil(sim.Now.Add(time.Second)); reason != StopQueueEmpty {
+			t.Errorf("expected %s, got %s", StopQueueEmpty, reason)
+		}
+	})
+
+	t.Run("Horizon reached", func(t *testing.T) {
+		sim := NewSimulation(WithHorizon(time.Time{}.Add(10 * time.Second)))
+		Ticker(sim, sim.Now, time.Second, func(*Simulation) {})
+
+		if reason := sim.RunUntil(sim.Now.Add(5 * time.Second)); reason != StopHorizonReached {
+			t.Errorf("expected %s, got %s", StopHorizonReached, reason)
+		}
+		if reason := sim.RunUntilDone(); reason != StopHorizonReached {
+			t.Errorf("expected %s, got %s", StopHorizonReached, reason)
+		}
+	})
+
+	t.Run("Budget exhausted", func(t *testing.T) {
+		sim := NewSimulation(WithEventBudget(5))
+		processed := 0
+		Ticker(sim, sim.Now, time.Second, func(*Simulation) { processed++ })
+
+		if reason := sim.RunUntilDone(); reason != StopBudgetExhausted {
+			t.Errorf("expected %s, got %s", StopBudgetExhausted, reason)
+		}
+		if processed != 5 {
+			t.Errorf("expected 5 events to be processed, got %d", processed)
+		}
+		if sim.Step() {
+			t.Error("expected no step to be taken after the budget is exhausted")
+		}
+	})
+}
+
+func TestStopOnlyAffectsTheCurrentRun(t *testing.T) {
+	sim := NewSimulation()
+	sim.After(time.Second, func(s *Simulation) { s.Stop() })
+	sim.After(2*time.Second, func(*Simulation) {})
+
+	if reason := sim.RunUntilDone(); reason != StopStopped {
+		t.Errorf("expected %s, got %s", StopStopped, reason)
+	}
+	if expected := (time.Time{}).Add(time.Second); !sim.Now.Equal(expected) {
+		t.Errorf("expected to stop at %s, got %s", expected, sim.Now)
+	}
+
+	// Resuming the simulation processes the remaining events.
+	if reason := sim.RunUntilDone(); reason != StopQueueEmpty {
+		t.Errorf("expected %s, got %s", StopQueueEmpty, reason)
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	t.Run("Cancelled before", func(t *testing.T) {
+		sim := NewSimulation()
+		ran := false
+		sim.After(time.Second, func(*Simulation) { ran = true })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if reason := sim.RunContext(ctx); reason != StopCancelled {
+			t.Errorf("expected %s, got %s", StopCancelled, reason)
+		}
+		if ran {
+			t.Error("expected no event to be processed")
+		}
+	})
+
+	t.Run("Cancelled during", func(t *testing.T) {
+		sim := NewSimulation()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		ticks := 0
+		Ticker(sim, sim.Now, time.Second, func(*Simulation) {
+			ticks++
+			if ticks == 10 {
+				cancel()
+			}
+		})
+		if reason := sim.RunContext(ctx); reason != StopCancelled {
+			t.Errorf("expected %s, got %s", StopCancelled, reason)
+		}
+		// The context is only checked periodically.
+		if ticks < 10 || ticks > 10+contextCheckInterval {
+			t.Errorf("expected the run to stop shortly after being cancelled, got %d ticks", ticks)
+		}
+	})
+
+	t.Run("Not cancelled", func(t *testing.T) {
+		sim := NewSimulation()
+		sim.After(time.Second, func(*Simulation) {})
+
+		if reason := sim.RunContext(context.Background()); reason != StopQueueEmpty {
+			t.Errorf("expected %s, got %s", StopQueueEmpty, reason)
+		}
+	})
+}
+
+// ExampleSimulation_RunFor shows how to drive a simulation in fixed-size chunks, for example to report progress.
+func ExampleSimulation_RunFor() {
+	sim := NewSimulation(WithHorizon(time.Time{}.Add(time.Minute)))
+	Ticker(sim, sim.Now, time.Second, func(*Simulation) {})
+
+	for range 3 {
+		processed := sim.RunFor(20 * time.Second)
+		fmt.Println("Processed", processed, "events")
+	}
+
+	// Output:
+	// Processed 21 events
+	// Processed 20 events
+	// Processed 20 events
+}
+
+func TestRunFor(t *testing.T) {
+	sim := NewSimulation()
+	Ticker(sim, sim.Now, time.Second, func(*Simulation) {})
+
+	// Warm-up period.
+	if processed := sim.RunFor(10 * time.Second); processed != 11 {
+		t.Errorf("expected 11 events to be processed, got %d", processed)
+	}
+	if processed := sim.RunFor(10 * time.Second); processed != 10 {
+		t.Errorf("expected 10 events to be processed, got %d", processed)
+	}
+	if expected := (time.Time{}).Add(20 * time.Second); !sim.Now.Equal(expected) {
+		t.Errorf("expected the simulation to be at %s, got %s", expected, sim.Now)
+	}
+}
+
+func TestRunSteps(t *testing.T) {
+	sim := NewSimulation()
+	for range 5 {
+		sim.After(time.Second, func(*Simulation) {})
+	}
+
+	if processed := sim.RunSteps(3); processed != 3 {
+		t.Errorf("expected 3 events to be processed, got %d", processed)
+	}
+	if processed := sim.RunSteps(3); processed != 2 {
+		t.Errorf("expected 2 events to be processed, got %d", processed)
+	}
+	if processed := sim.RunSteps(3); processed != 0 {
+		t.Errorf("expected 0 events to be processed, got %d", processed)
+	}
+}
+
+func TestRunStepsStopped(t *testing.T) {
+	sim := NewSimulation()
+	sim.After(time.Second, func(s *Simulation) { s.Stop() })
+	sim.After(time.Second, func(*Simulation) {})
+
+	if processed := sim.RunSteps(2); processed != 1 {
+		t.Errorf("expected 1 event to be processed, got %d", processed)
+	}
+}
+
+func TestRunWhile(t *testing.T) {
+	sim := NewSimulation()
+	count := 0
+	Ticker(sim, sim.Now, time.Second, func(*Simulation) { count++ })
+
+	processed := sim.RunWhile(func(*Simulation) bool { return count < 7 })
+	if processed != 7 {
+		t.Errorf("expected 7 events to be processed, got %d", processed)
+	}
+	if count != 7 {
+		t.Errorf("expected count to be 7, got %d", count)
+	}
+}
+
+func TestAdvanceTo(t *testing.T) {
+	var start time.Time
+
+	t.Run("Idle tail", func(t *testing.T) {
+		sim := NewSimulation()
+		sim.After(5*time.Second, func(*Simulation) {})
+
+		if reason := sim.AdvanceTo(start.Add(10 * time.Second)); reason != StopQueueEmpty {
+			t.Errorf("expected %s, got %s", StopQueueEmpty, reason)
+		}
+		if expected := start.Add(10 * time.Second); !sim.Now.Equal(expected) {
+			t.Errorf("expected the clock to be at %s, got %s", expected, sim.Now)
+		}
+
+		// Scheduling is relative to the advanced clock.
+		var ranAt time.Time
+		sim.After(time.Second, func(s *Simulation) { ranAt = s.Now })
+		sim.RunUntilDone()
+		if expected := start.Add(11 * time.Second); !ranAt.Equal(expected) {
+			t.Errorf("expected event to run at %s, got %s", expected, ranAt)
+		}
+	})
+
+	t.Run("Events after the target", func(t *testing.T) {
+		sim := NewSimulation()
+		sim.After(5*time.Second, func(*Simulation) {})
+		ran := false
+		sim.After(15*time.Second, func(*Simulation) { ran = true })
+
+		if reason := sim.AdvanceTo(start.Add(10 * time.Second)); reason != StopHorizonReached {
+			t.Errorf("expected %s, got %s", StopHorizonReached, reason)
+		}
+		if expected := start.Add(10 * time.Second); !sim.Now.Equal(expected) {
+			t.Errorf("expected the clock to be at %s, got %s", expected, sim.Now)
+		}
+		if ran {
+			t.Error("expected the event after the target to not run")
+		}
+	})
+
+	t.Run("Event at the target", func(t *testing.T) {
+		sim := NewSimulation()
+		ran := false
+		sim.After(10*time.Second, func(*Simulation) { ran = true })
+
+		sim.AdvanceTo(start.Add(10 * time.Second))
+		if !ran {
+			t.Error("expected the event at the target to run")
+		}
+	})
+
+	t.Run("Empty simulation", func(t *testing.T) {
+		sim := NewSimulation()
+		sim.AdvanceTo(start.Add(10 * time.Second))
+		if expected := start.Add(10 * time.Second); !sim.Now.Equal(expected) {
+			t.Errorf("expected the clock to be at %s, got %s", expected, sim.Now)
+		}
+	})
+
+	t.Run("Never backwards", func(t *testing.T) {
+		sim := NewSimulation(WithStartTime(start.Add(time.Minute)))
+		sim.AdvanceTo(start)
+		if expected := start.Add(time.Minute); !sim.Now.Equal(expected) {
+			t.Errorf("expected the clock to be at %s, got %s", expected, sim.Now)
+		}
+	})
+
+	t.Run("Not past the horizon", func(t *testing.T) {
+		sim := NewSimulation(WithHorizon(start.Add(5 * time.Second)))
+		sim.AdvanceTo(start.Add(10 * time.Second))
+		if expected := start.Add(5 * time.Second); !sim.Now.Equal(expected) {
+			t.Errorf("expected the clock to be at %s, got %s", expected, sim.Now)
+		}
+	})
+
+	t.Run("Stopped", func(t *testing.T) {
+		sim := NewSimulation()
+		sim.After(5*time.Second, func(s *Simulation) { s.Stop() })
+
+		if reason := sim.AdvanceTo(start.Add(10 * time.Second)); reason != StopStopped {
+			t.Errorf("expected %s, got %s", StopStopped, reason)
+		}
+		if expected := start.Add(5 * time.Second); !sim.Now.Equal(expected) {
+			t.Errorf("expected the clock to be at %s, got %s", expected, sim.Now)
+		}
+	})
+}
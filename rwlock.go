@@ -0,0 +1,198 @@
+package steps
+
+import "time"
+
+// RWPreference decides whether readers or writers go first when both are waiting for an RWLock. See WithRWPreference.
+type RWPreference int
+
+const (
+	// PreferWriters makes new readers wait while a writer is waiting, so that a steady stream of readers cannot starve the writers. Once the writer unlocks, the next waiting writer goes first. This is the default.
+	PreferWriters RWPreference = iota
+
+	// PreferReaders lets new readers in as long as no writer holds the lock, even if writers are waiting. This maximizes concurrency, but a steady stream of readers can starve the writers.
+	PreferReaders
+)
+
+// WithRWPreference sets whether readers or writers go first when both are waiting for an RWLock. The default is PreferWriters. It only applies to an RWLock.
+func WithRWPreference(p RWPreference) ResourceOption {
+	return func(o *resourceOptions) {
+		o.rwPreference = p
+		o.given = append(o.given, "WithRWPreference")
+	}
+}
+
+// RWLockStats are statistics about how an RWLock has been used, split by readers and writers. See WithStats.
+type RWLockStats struct {
+	// Read counts the readers holding the lock as being in use, and Write the writer.
+	Read  ResourceStats
+	Write ResourceStats
+}
+
+// RWLock is a reader/writer lock. It can be held by any number of readers or by a single writer, which is useful to model databases and caches. Whether readers or writers go first when both are waiting is decided by WithRWPreference.
+//
+// Locking returns a Request, which can be used to stop waiting for the lock. A request which has been handed the lock can still be cancelled until its action runs, which unlocks it. Once the action has run, the lock must be unlocked as usual.
+type RWLock struct {
+	sim        *Simulation
+	preference RWPreference
+
+	readers int
+	writer  bool
+
+	// waitingReaders and waitingWriters hold the actions waiting for the lock. requested holds when each of them started waiting.
+	waitingReaders *Condition
+	waitingWriters *Condition
+	requested      map[*Condition]map[ConditionActionID]time.Time
+
+	// readStats and writeStats record statistics, if enabled using WithStats.
+	readStats  *statsRecorder
+	writeStats *statsRecorder
+}
+
+// NewRWLock creates a new, unlocked, reader/writer lock.
+func NewRWLock(sim *Simulation, opts ...ResourceOption) *RWLock {
+	o := newResourceOptions("RWLock", opts, "WithRWPreference")
+	l := &RWLock{
+		sim:            sim,
+		preference:     o.rwPreference,
+		waitingReaders: NewCondition(sim),
+		waitingWriters: NewCondition(sim),
+	}
+	l.requested = map[*Condition]map[ConditionActionID]time.Time{
+		l.waitingReaders: make(map[ConditionActionID]time.Time),
+		l.waitingWriters: make(map[ConditionActionID]time.Time),
+	}
+	l.readStats = newStatsRecorder(sim, o, 0, func() (float64, int) {
+		return float64(l.readers), l.waitingReaders.Waiting()
+	})
+	l.writeStats = newStatsRecorder(sim, o, 1, func() (float64, int) {
+		writers := 0.0
+		if l.writer {
+			writers = 1
+		}
+		return writers, l.waitingWriters.Waiting()
+	})
+	return l
+}
+
+// RLock locks the lock for reading and schedules a to run as soon as possible. If a writer holds the lock, or is waiting for it when using PreferWriters, a is scheduled once the lock can be read. Do not forget to call RUnlock() when the action is done.
+func (l *RWLock) RLock(a Action) *Request {
+	canRead := !l.writer && (l.preference == PreferReaders || l.waitingWriters.Waiting() == 0)
+	if canRead {
+		l.readers++
+		l.readStats.serve(l.sim.Now)
+		return l.granted(a, l.RUnlock)
+	}
+	return l.wait(l.waitingReaders, a)
+}
+
+// Lock locks the lock for writing and schedules a to run as soon as possible. If the lock is held by anyone, a is scheduled once the lock can be written. Do not forget to call Unlock() when the action is done.
+func (l *RWLock) Lock(a Action) *Request {
+	canWrite := !l.writer && l.readers == 0 && l.waitingWriters.Waiting() == 0 && l.waitingReaders.Waiting() == 0
+	if canWrite {
+		l.writer = true
+		l.writeStats.serve(l.sim.Now)
+		return l.granted(a, l.Unlock)
+	}
+	return l.wait(l.waitingWriters, a)
+}
+
+// RUnlock unlocks the lock for one reader. Unlocking a lock that isn't held by any reader is a misuse (see MisusePolicy), and does nothing.
+func (l *RWLock) RUnlock() {
+	if l.readers == 0 {
+		l.sim.misuse("read-unlocked a lock that isn't held by any reader")
+		return
+	}
+	l.readers--
+	l.dispatch()
+}
+
+// Unlock unlocks the lock for the writer. Unlocking a lock that isn't held by a writer is a misuse (see MisusePolicy), and does nothing.
+func (l *RWLock) Unlock() {
+	if !l.writer {
+		l.sim.misuse("unlocked a lock that isn't held by a writer")
+		return
+	}
+	l.writer = false
+	l.dispatch()
+}
+
+// Readers returns the number of readers holding the lock.
+func (l *RWLock) Readers() int {
+	return l.readers
+}
+
+// Locked returns true if a writer holds the lock.
+func (l *RWLock) Locked() bool {
+	return l.writer
+}
+
+// Stats returns statistics about how the lock has been used. Returns zero RWLockStats unless the lock was created using WithStats.
+func (l *RWLock) Stats() RWLockStats {
+	return RWLockStats{
+		Read:  l.readStats.stats(),
+		Write: l.writeStats.stats(),
+	}
+}
+
+// granted schedules a, which has been handed the lock right away. If the request is cancelled before a runs, the lock is given back using unlock.
+func (l *RWLock) granted(a Action, unlock func()) *Request {
+	event := l.sim.Immediately(a)
+	l.updateStats()
+	return newRequest(func() bool {
+		if !l.sim.Cancel(event) {
+			return false
+		}
+		unlock()
+		return true
+	})
+}
+
+// wait makes a wait for the lock using cond.
+func (l *RWLock) wait(cond *Condition, a Action) *Request {
+	id := cond.Wait(a)
+	l.requested[cond][id] = l.sim.Now
+	l.updateStats()
+	return newRequest(func() bool {
+		if !cond.Cancel(id) {
+			return false
+		}
+		delete(l.requested[cond], id)
+		// A cancelled writer might have been holding back readers.
+		l.dispatch()
+		return true
+	})
+}
+
+// dispatch hands the lock over to waiting readers or writers, according to the preference.
+func (l *RWLock) dispatch() {
+	defer l.updateStats()
+	if l.writer {
+		return
+	}
+	readersFirst := l.preference == PreferReaders || l.waitingWriters.Waiting() == 0
+	if readersFirst && l.waitingReaders.Waiting() > 0 {
+		for id := range l.waitingReaders.ordered() {
+			l.readers++
+			l.wake(l.waitingReaders, id, l.readStats)
+		}
+		return
+	}
+	if l.readers == 0 {
+		if id, ok := l.waitingWriters.front(); ok {
+			l.writer = true
+			l.wake(l.waitingWriters, id, l.writeStats)
+		}
+	}
+}
+
+// wake wakes up the action waiting for cond with the given ID, which has been handed the lock.
+func (l *RWLock) wake(cond *Condition, id ConditionActionID, stats *statsRecorder) {
+	stats.serve(l.requested[cond][id])
+	delete(l.requested[cond], id)
+	cond.signalID(id)
+}
+
+func (l *RWLock) updateStats() {
+	l.readStats.update()
+	l.writeStats.update()
+}
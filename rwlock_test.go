@@ -0,0 +1,185 @@
+package steps
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// ExampleRWLock simulates a database table that two clients read for ten (10) minutes each, while a third client wants to update it. The update waits for the reads, and holds back the reads that come after it.
+func ExampleRWLock() {
+	sim := NewSimulation()
+	table := NewRWLock(sim)
+
+	read := func(client int) {
+		table.RLock(func(sim *Simulation) {
+			fmt.Println(sim.Now, "Client", client, "reading")
+			sim.After(10*time.Minute, func(*Simulation) { table.RUnlock() })
+		})
+	}
+	read(0)
+	read(1)
+	sim.After(time.Minute, func(*Simulation) {
+		table.Lock(func(sim *Simulation) {
+			fmt.Println(sim.Now, "Client 2 updating")
+			sim.After(5*time.Minute, func(*Simulation) { table.Unlock() })
+		})
+	})
+	sim.After(2*time.Minute, func(*Simulation) { read(3) })
+	sim.RunUntilDone()
+
+	// Output:
+	// 0001-01-01 00:00:00 +0000 UTC Client 0 reading
+	// 0001-01-01 00:00:00 +0000 UTC Client 1 reading
+	// 0001-01-01 00:10:00 +0000 UTC Client 2 updating
+	// 0001-01-01 00:15:00 +0000 UTC Client 3 reading
+}
+
+func TestRWLockPreference(t *testing.T) {
+	for _, test := range []struct {
+		preference RWPreference
+		expected   []string
+	}{
+		{PreferWriters, []string{"0s reader", "10m0s writer", "15m0s late reader"}},
+		{PreferReaders, []string{"0s reader", "2m0s late reader", "12m0s writer"}},
+	} {
+		sim := NewSimulation()
+		l := NewRWLock(sim, WithRWPreference(test.preference))
+
+		var order []string
+		hold := func(name string, lock func(Action) *Request, unlock func(), d time.Duration) {
+			lock(func(sim *Simulation) {
+				order = append(order, fmt.Sprint(sim.Now.Sub(time.Time{}), " ", name))
+				sim.After(d, func(*Simulation) { unlock() })
+			})
+		}
+		hold("reader", l.RLock, l.RUnlock, 10*time.Minute)
+		sim.After(time.Minute, func(*Simulation) { hold("writer", l.Lock, l.Unlock, 5*time.Minute) })
+		sim.After(2*time.Minute, func(*Simulation) { hold("late reader", l.RLock, l.RUnlock, 10*time.Minute) })
+		sim.RunUntilDone()
+
+		if fmt.Sprint(order) != fmt.Sprint(test.expected) {
+			t.Errorf("preference %d: expected %v, got %v", test.preference, test.expected, order)
+		}
+		if l.Readers() != 0 || l.Locked() {
+			t.Errorf("preference %d: expected the lock to be free, got %d readers and locked %v", test.preference, l.Readers(), l.Locked())
+		}
+	}
+}
+
+func TestRWLockWriterWakesAllReaders(t *testing.T) {
+	sim := NewSimulation()
+	l := NewRWLock(sim)
+
+	l.Lock(func(sim *Simulation) {
+		sim.After(time.Second, func(*Simulation) { l.Unlock() })
+	})
+	read := 0
+	for range 3 {
+		l.RLock(func(*Simulation) { read++ })
+	}
+	sim.RunUntilDone()
+
+	if read != 3 {
+		t.Errorf("expected 3 readers, got %d", read)
+	}
+	if l.Readers() != 3 {
+		t.Errorf("expected 3 readers to hold the lock, got %d", l.Readers())
+	}
+}
+
+func TestRWLockCancelWaitingWriterLetsReadersIn(t *testing.T) {
+	sim := NewSimulation()
+	l := NewRWLock(sim)
+
+	l.RLock(func(*Simulation) {})
+	writer := l.Lock(func(*Simulation) {
+		t.Error("expected the cancelled writer to never run")
+	})
+	read := false
+	l.RLock(func(*Simulation) { read = true })
+	if !writer.Cancel() {
+		t.Error("expected to cancel the waiting writer")
+	}
+	if writer.Cancel() {
+		t.Error("expected a second cancel to fail")
+	}
+	sim.RunUntilDone()
+
+	if !read {
+		t.Error("expected the reader behind the cancelled writer to read")
+	}
+}
+
+func TestRWLockCancelGrantedLock(t *testing.T) {
+	sim := NewSimulation()
+	l := NewRWLock(sim)
+
+	writer := l.Lock(func(*Simulation) {
+		t.Error("expected the cancelled writer to never run")
+	})
+	if !writer.Cancel() {
+		t.Error("expected to cancel the writer before it ran")
+	}
+	if l.Locked() {
+		t.Error("expected the lock to be given back")
+	}
+
+	reader := l.RLock(func(*Simulation) {})
+	sim.RunUntilDone()
+	if reader.Cancel() {
+		t.Error("expected to not cancel a reader that has run")
+	}
+	if l.Readers() != 1 {
+		t.Errorf("expected 1 reader, got %d", l.Readers())
+	}
+}
+
+func TestRWLockUnlockWithoutLockIsReported(t *testing.T) {
+	sim := NewSimulation(WithMisusePolicy(MisuseReport))
+	l := NewRWLock(sim)
+
+	l.Unlock()
+	l.RUnlock()
+	l.RLock(func(*Simulation) {})
+	sim.RunUntilDone()
+	l.Unlock()
+
+	if sim.Misuses() != 3 {
+		t.Errorf("expected 3 misuses, got %d", sim.Misuses())
+	}
+	if l.Readers() != 1 {
+		t.Errorf("expected the reader to still hold the lock, got %d readers", l.Readers())
+	}
+}
+
+func TestRWLockStats(t *testing.T) {
+	sim := NewSimulation()
+	l := NewRWLock(sim, WithStats())
+
+	l.RLock(func(sim *Simulation) {
+		sim.After(time.Hour, func(*Simulation) { l.RUnlock() })
+	})
+	l.Lock(func(sim *Simulation) {
+		sim.After(time.Hour, func(*Simulation) { l.Unlock() })
+	})
+	sim.RunUntilDone()
+	sim.AdvanceTo(sim.Now.Add(2 * time.Hour))
+
+	s := l.Stats()
+	if s.Read.Served != 1 || s.Write.Served != 1 {
+		t.Errorf("expected 1 reader and 1 writer served, got %d and %d", s.Read.Served, s.Write.Served)
+	}
+	if s.Read.MeanInUse != 0.25 {
+		t.Errorf("expected a mean of 0.25 readers, got %g", s.Read.MeanInUse)
+	}
+	if s.Write.Utilization != 0.25 {
+		t.Errorf("expected a write utilization of 0.25, got %g", s.Write.Utilization)
+	}
+	if s.Write.Waits.Max != time.Hour {
+		t.Errorf("expected the writer to wait 1h0m0s, got %v", s.Write.Waits.Max)
+	}
+	if s.Write.MeanQueueLength != 0.25 {
+		t.Errorf("expected a mean of 0.25 waiting writers, got %g", s.Write.MeanQueueLength)
+	}
+}
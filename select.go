@@ -0,0 +1,151 @@
+package steps
+
+import "time"
+
+// Select waits for the first of several cases, such as a condition being signaled, a message arriving in a mailbox or a timeout, and runs the action of that case only. As soon as one case fires, the others are cancelled, so that they don't consume a signal or an item meant for someone else.
+//
+// Cases are added using the builder methods, and SelectGet and SelectReceive for stores and mailboxes, and are waited for once the select is started using Start. A Select is also a Command, so a process can yield it to wait for the first case, and then use Fired to find out which one it was.
+type Select struct {
+	sim   *Simulation
+	cases []func(i int) *Request
+
+	// waiting holds the requests of the cases that have started waiting.
+	waiting []*Request
+
+	// fired is the index of the case that has fired, or -1 if none has.
+	fired     int
+	started   bool
+	cancelled bool
+
+	// then is run after the action of the case that has fired. It is used to resume a process.
+	then Action
+}
+
+// NewSelect creates a new select without any cases.
+func NewSelect(sim *Simulation) *Select {
+	return &Select{sim: sim, fired: -1}
+}
+
+// Condition adds a case that fires when c is signaled, and then runs a. a may be nil. Note that a Signal of c is passed on to the next action waiting for c if another case has already fired.
+func (s *Select) Condition(c *Condition, a Action) *Select {
+	return s.add(func(i int) *Request {
+		id := c.WaitUntil(func() bool { return s.claim(i) }, func(sim *Simulation) {
+			s.run(sim, a)
+		})
+		return newRequest(func() bool { return c.Cancel(id) })
+	})
+}
+
+// Timeout adds a case that fires after the duration d, counted from when the select is started, and then runs a. a may be nil.
+func (s *Select) Timeout(d time.Duration, a Action) *Select {
+	return s.add(func(i int) *Request {
+		event := s.sim.After(d, func(sim *Simulation) {
+			if s.claim(i) {
+				s.run(sim, a)
+			}
+		})
+		return newRequest(func() bool { return s.sim.Cancel(event) })
+	})
+}
+
+// SelectGet adds a case to s that fires when an item can be got from st, and then passes the item to a. a may be nil. Returns s, for chaining.
+func SelectGet[T any](s *Select, st *Store[T], a func(*Simulation, T)) *Select {
+	return selectStore(s, &st.store, nil, a)
+}
+
+// SelectReceive adds a case to s that fires when a message is received from m, and then passes the message to a. a may be nil. If match isn't nil, only messages for which it returns true are received, like for Mailbox.ReceiveMatching. Returns s, for chaining.
+func SelectReceive[T any](s *Select, m *Mailbox[T], match func(T) bool, a func(*Simulation, T)) *Select {
+	return selectStore(s, &m.messages, match, a)
+}
+
+// selectStore adds a case to s that fires when an item for which match returns true can be got from st. A nil match accepts any item.
+func selectStore[T any](s *Select, st *store[T], match func(T) bool, a func(*Simulation, T)) *Select {
+	return s.add(func(i int) *Request {
+		// The item is only taken if the select claims it, so that it is left for others once another case has fired.
+		filter := func(item T) bool {
+			return (match == nil || match(item)) && s.claim(i)
+		}
+		return st.get(filter, func(sim *Simulation, item T) {
+			s.run(sim, func(sim *Simulation) {
+				if a != nil {
+					a(sim, item)
+				}
+			})
+		})
+	})
+}
+
+// Start starts waiting for all cases. If a case can fire right away, for example because a store already holds an item, the cases after it are never waited for. Panics if the select has already been started.
+func (s *Select) Start() {
+	if s.started {
+		panic("select has already been started")
+	}
+	s.started = true
+	for i, c := range s.cases {
+		if s.fired >= 0 || s.cancelled {
+			return
+		}
+		s.waiting = append(s.waiting, c(i))
+	}
+}
+
+// Cancel stops waiting for all cases. Returns false if a case has already fired, in which case its action runs (or has run) as usual, or if the select was already cancelled.
+func (s *Select) Cancel() bool {
+	if s.fired >= 0 || s.cancelled {
+		return false
+	}
+	s.cancelled = true
+	s.cancelExcept(-1)
+	return true
+}
+
+// Fired returns the index of the case that has fired, in the order the cases were added, and true. Returns false if no case has fired yet.
+func (s *Select) Fired() (int, bool) {
+	return s.fired, s.fired >= 0
+}
+
+func (s *Select) add(c func(i int) *Request) *Select {
+	if s.started {
+		panic("cannot add a case to a select that has already been started")
+	}
+	s.cases = append(s.cases, c)
+	return s
+}
+
+// claim makes case i the one that fires, and cancels the others. Returns false if another case has already fired or the select has been cancelled.
+func (s *Select) claim(i int) bool {
+	if s.fired >= 0 || s.cancelled {
+		return false
+	}
+	s.fired = i
+	s.cancelExcept(i)
+	return true
+}
+
+// cancelExcept cancels all cases that have started waiting, except case i.
+func (s *Select) cancelExcept(i int) {
+	for j, req := range s.waiting {
+		if j != i {
+			req.Cancel()
+		}
+	}
+}
+
+// run runs the action of the case that has fired, followed by then.
+func (s *Select) run(sim *Simulation, a Action) {
+	if a != nil {
+		a(sim)
+	}
+	if s.then != nil {
+		s.then(sim)
+	}
+}
+
+func (s *Select) start(p *Process) {
+	s.then = p.resume
+	s.Start()
+}
+
+func (s *Select) cancel(p *Process) bool {
+	return s.Cancel()
+}
@@ -0,0 +1,181 @@
+package steps
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// ExampleSelect simulates a client that sends a request to a server, and gives up if there is no reply within a second. The server takes two (2) seconds to reply to the first request, and half a second to reply to the second.
+func ExampleSelect() {
+	sim := NewSimulation()
+	requests := NewMailbox[int](sim, 0)
+	replies := NewMailbox[int](sim, 0)
+
+	var serve func()
+	serve = func() {
+		requests.Receive(func(sim *Simulation, request int) {
+			delay := 500 * time.Millisecond
+			if request == 0 {
+				delay = 2 * time.Second
+			}
+			replies.SendAfter(delay, request, nil)
+			serve()
+		})
+	}
+	serve()
+
+	NewProcess(sim, func(p *Process, yield func(Command) bool) {
+		for request := range 2 {
+			requests.Send(request, nil)
+			sel := NewSelect(p.Sim()).Timeout(time.Second, nil)
+			SelectReceive(sel, replies, func(reply int) bool { return reply == request }, nil)
+			if !yield(sel) {
+				return
+			}
+			if i, _ := sel.Fired(); i == 0 {
+				fmt.Println(p.Sim().Now, "Request", request, "timed out")
+			} else {
+				fmt.Println(p.Sim().Now, "Request", request, "replied")
+			}
+		}
+	})
+	sim.RunUntilDone()
+
+	// Output:
+	// 0001-01-01 00:00:01 +0000 UTC Request 0 timed out
+	// 0001-01-01 00:00:01.5 +0000 UTC Request 1 replied
+}
+
+func TestSelectFiresExactlyOneCase(t *testing.T) {
+	sim := NewSimulation()
+	a := NewStore[string](sim, 0)
+	b := NewMailbox[string](sim, 0)
+	a.Put("a", nil)
+	b.Send("b", nil)
+
+	var got []string
+	sel := NewSelect(sim).Timeout(0, func(*Simulation) { got = append(got, "timeout") })
+	SelectGet(sel, a, func(_ *Simulation, item string) { got = append(got, item) })
+	SelectReceive(sel, b, nil, func(_ *Simulation, msg string) { got = append(got, msg) })
+	sel.Start()
+	sim.RunUntilDone()
+
+	if fmt.Sprint(got) != "[a]" {
+		t.Errorf("expected only [a], got %v", got)
+	}
+	if i, ok := sel.Fired(); !ok || i != 1 {
+		t.Errorf("expected case 1 to fire, got %d", i)
+	}
+	if b.Len() != 1 {
+		t.Errorf("expected the message to be left in the mailbox, got %d messages", b.Len())
+	}
+}
+
+func TestSelectCancelsOtherCases(t *testing.T) {
+	sim := NewSimulation()
+	c := NewCondition(sim)
+	st := NewStore[int](sim, 0)
+
+	var fired []string
+	sel := NewSelect(sim).
+		Condition(c, func(*Simulation) { fired = append(fired, "condition") }).
+		Timeout(time.Minute, func(*Simulation) { fired = append(fired, "timeout") })
+	SelectGet(sel, st, func(*Simulation, int) { fired = append(fired, "store") })
+	sel.Start()
+
+	other := false
+	c.Wait(func(*Simulation) { other = true })
+	sim.After(time.Second, func(*Simulation) { c.Signal() })
+	sim.After(2*time.Second, func(*Simulation) {
+		c.Signal()
+		st.Put(1, nil)
+	})
+	sim.RunUntilDone()
+
+	if fmt.Sprint(fired) != "[condition]" {
+		t.Errorf("expected only [condition], got %v", fired)
+	}
+	if !other {
+		t.Error("expected the second signal to wake up the other waiting action")
+	}
+	if st.Len() != 1 {
+		t.Errorf("expected the item to be left in the store, got %d items", st.Len())
+	}
+	if sim.Now.Sub(time.Time{}) != 2*time.Second {
+		t.Errorf("expected the timeout to be cancelled, got the clock at %v", sim.Now.Sub(time.Time{}))
+	}
+}
+
+func TestSelectOnSameMailbox(t *testing.T) {
+	sim := NewSimulation()
+	m := NewMailbox[int](sim, 0)
+
+	var got []string
+	sel := NewSelect(sim)
+	SelectReceive(sel, m, func(msg int) bool { return msg%2 == 0 }, func(_ *Simulation, msg int) {
+		got = append(got, fmt.Sprint("even ", msg))
+	})
+	SelectReceive(sel, m, func(msg int) bool { return msg%2 == 1 }, func(_ *Simulation, msg int) {
+		got = append(got, fmt.Sprint("odd ", msg))
+	})
+	sel.Start()
+	m.Send(1, nil)
+	m.Send(2, nil)
+	sim.RunUntilDone()
+
+	if fmt.Sprint(got) != "[odd 1]" {
+		t.Errorf("expected only [odd 1], got %v", got)
+	}
+	if msg, ok := m.TryReceive(); !ok || msg != 2 {
+		t.Errorf("expected 2 to be left in the mailbox, got %d", msg)
+	}
+}
+
+func TestSelectCancel(t *testing.T) {
+	sim := NewSimulation()
+	c := NewCondition(sim)
+
+	sel := NewSelect(sim).
+		Condition(c, func(*Simulation) { t.Error("expected the condition case to be cancelled") }).
+		Timeout(time.Second, func(*Simulation) { t.Error("expected the timeout to be cancelled") })
+	sel.Start()
+	if !sel.Cancel() {
+		t.Error("expected to cancel the select")
+	}
+	if sel.Cancel() {
+		t.Error("expected a second cancel to fail")
+	}
+	c.Signal()
+	sim.RunUntilDone()
+
+	if _, ok := sel.Fired(); ok {
+		t.Error("expected no case to fire")
+	}
+	if c.Waiting() != 0 {
+		t.Errorf("expected nothing waiting for the condition, got %d", c.Waiting())
+	}
+}
+
+func TestProcessSelectInterrupted(t *testing.T) {
+	sim := NewSimulation()
+	c := NewCondition(sim)
+
+	var cause any
+	p := NewProcess(sim, func(p *Process, yield func(Command) bool) {
+		yield(NewSelect(p.Sim()).Condition(c, nil).Timeout(time.Hour, nil))
+		cause, _ = p.Interrupted()
+	})
+	sim.After(time.Second, func(*Simulation) { p.Interrupt("stop") })
+	sim.RunUntilDone()
+
+	if cause != "stop" {
+		t.Errorf("expected to be interrupted, got %v", cause)
+	}
+	if c.Waiting() != 0 {
+		t.Errorf("expected nothing waiting for the condition, got %d", c.Waiting())
+	}
+	if sim.Now.Sub(time.Time{}) != time.Second {
+		t.Errorf("expected the timeout to be cancelled, got the clock at %v", sim.Now.Sub(time.Time{}))
+	}
+}
@@ -1,77 +1,330 @@
 package steps
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
 
-// EventID is the ID of a scheduled event. It is mostly used if you need to cancel a scheduled event before it is executed.
-type EventID int
+// EventID is the ID of a scheduled event. It is mostly used if you need to cancel a scheduled event before it is executed. The zero EventID never refers to an event.
+//
+// IDs are handles that are reused once an event has been executed or cancelled, but with a new generation, so an old ID never refers to a newer event.
+type EventID uint64
+
+// slot returns the part of the ID that is reused between events.
+func (id EventID) slot() uint32 {
+	return uint32(id)
+}
+
+// eventIDs hands out EventIDs. The slots of the IDs are kept dense so that they can be used as indices into slices, which avoids per-event map writes in the default queue.
+type eventIDs struct {
+	// generations holds the current generation of each slot.
+	generations []uint32
+
+	// free holds the slots that are not used by any event in the queue.
+	free []uint32
+}
+
+// get returns a new ID.
+func (p *eventIDs) get() EventID {
+	var slot uint32
+	if n := len(p.free); n > 0 {
+		slot = p.free[n-1]
+		p.free = p.free[:n-1]
+	} else {
+		slot = uint32(len(p.generations))
+		p.generations = append(p.generations, 1)
+	}
+	return EventID(uint64(p.generations[slot])<<32 | uint64(slot))
+}
+
+// put makes the slot of an ID, whose event is no longer in the queue, available for reuse.
+func (p *eventIDs) put(id EventID) {
+	slot := id.slot()
+	p.generations[slot]++
+	if p.generations[slot] == 0 {
+		// Generation zero is reserved so that the zero EventID never refers to an event.
+		p.generations[slot] = 1
+	}
+	p.free = append(p.free, slot)
+}
 
 // Simulation runs a discrete event simulation.
 type Simulation struct {
 	// Now represents the current point in time in the simulation. It is not recommended to modify this value during a simulation.
 	Now time.Time
 
-	// nextID is incremented for each event scheduled to the simulation. It is used to sort events with the same time.
-	nextID EventID
+	// nextSeq is incremented for each event scheduled to the simulation. It is used to sort events with the same time.
+	nextSeq uint64
+
+	// ids hands out the IDs of scheduled events.
+	ids eventIDs
+
+	// Rand is a random number generator for the simulation, seeded using WithSeed. See also NewRand.
+	Rand *rand.Rand
 
 	// queue is the queue of future events to be processed.
-	queue *eventQueue
+	queue Queue
+
+	// seed is the master random seed of the simulation. nextStream is the stream to be returned by the next call to NewRand.
+	seed       uint64
+	nextStream uint64
+
+	// horizon is the time after which no events are processed, if hasHorizon is set.
+	horizon    time.Time
+	hasHorizon bool
+
+	// hooks are called when things happen in the simulation.
+	hooks []Hooks
+
+	// pastPolicy decides what happens to events scheduled in the past. pastEvents counts them when using PastWarn.
+	pastPolicy PastPolicy
+	pastEvents int
+
+	// processed is the number of events processed so far. budget is the maximum number of events to process, if hasBudget is set.
+	processed int
+	budget    int
+	hasBudget bool
+
+	// stopped is set by Stop to make the current run return.
+	stopped bool
+
+	// misusePolicy decides what happens when a resource is misused. misuses counts misuses when using MisuseReport.
+	misusePolicy MisusePolicy
+	misuses      int
+
+	// trackLeaks is set by WithLeakTracking. conditions and held are then used for the leak report.
+	trackLeaks bool
+	conditions []*Condition
+	held       []*Acquisition
 }
 
-// NewSimulation creates a new simulation.
-func NewSimulation() *Simulation {
-	// Currently, the zero value of Simulation is a valid simulation. However, this function exists to this library a bit more forward compatible in case zero values are no longer valid.
-	return &Simulation{queue: newEventQueue()}
+// ErrScheduledInPast is returned by Simulation.ScheduleE when an event is scheduled before the current time and the PastPolicy of the simulation is PastReject.
+var ErrScheduledInPast = errors.New("event scheduled in the past")
+
+// PastPolicy decides what happens when an event is scheduled strictly before the current time of a simulation, which is usually a bug in the model. See WithPastPolicy. Events scheduled using Simulation.Immediately are never considered to be in the past.
+type PastPolicy int
+
+const (
+	// PastAllow processes events scheduled in the past as soon as possible, without moving the clock backwards. This is the default.
+	PastAllow PastPolicy = iota
+
+	// PastPanic panics when an event is scheduled in the past.
+	PastPanic
+
+	// PastReject doesn't schedule events in the past. Simulation.ScheduleE returns an error wrapping ErrScheduledInPast, while Simulation.Schedule panics with it.
+	PastReject
+
+	// PastClamp schedules events in the past at the current time instead.
+	PastClamp
+
+	// PastWarn processes events in the past like PastAllow, but counts them (see Simulation.PastEvents) and calls the OnPastEvent hook.
+	PastWarn
+)
+
+// NewSimulation creates a new simulation, configured using the given options. Calling it without any options gives a simulation starting at the zero time.Time, using the default queue, with a random seed of zero and without any horizon.
+func NewSimulation(opts ...Option) *Simulation {
+	s := &Simulation{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.queue == nil {
+		s.queue = newEventQueue()
+	}
+	s.Rand = rand.New(rand.NewPCG(s.seed, 0))
+	s.nextStream = 1
+	return s
+}
+
+// NewRand returns a new random number generator derived from the seed of the simulation (see WithSeed). Every call returns an independent stream of random numbers. Giving each entity of a model its own stream makes sure that adding or removing an entity doesn't change the random numbers seen by the others.
+func (s *Simulation) NewRand() *rand.Rand {
+	r := rand.New(rand.NewPCG(s.seed, s.nextStream))
+	s.nextStream++
+	return r
 }
 
-// Step advances the simulation by one event. It returns true if the simulation advanced, false if there were no events to process.
+// Step advances the simulation by one event. It returns true if the simulation advanced, false if there were no events to process, the next event is after the horizon (see WithHorizon), or the event budget is exhausted (see WithEventBudget).
 func (s *Simulation) Step() bool {
-	if s.queue.Len() == 0 {
+	if _, blocked := s.blocked(time.Time{}, false); blocked {
 		return false
 	}
+	s.process()
+	return true
+}
+
+// blocked returns whether the next event cannot be processed, and why. If hasUntil is set, events after until cannot be processed.
+func (s *Simulation) blocked(until time.Time, hasUntil bool) (StopReason, bool) {
+	if s.queue.Len() == 0 {
+		return StopQueueEmpty, true
+	}
+	if s.hasBudget && s.processed >= s.budget {
+		return StopBudgetExhausted, true
+	}
+	if s.hasHorizon || hasUntil {
+		when := s.queue.Peek().Event.When
+		if s.hasHorizon && when.After(s.horizon) {
+			return StopHorizonReached, true
+		}
+		if hasUntil && when.After(until) {
+			return StopHorizonReached, true
+		}
+	}
+	return 0, false
+}
+
+// process processes the next event, which must exist.
+func (s *Simulation) process() {
 	e := s.queue.Pop()
+	s.ids.put(e.ID)
+	s.processed++
 	if e.Event.When.After(s.Now) {
 		// Never allow s.Now to go backwards in time.
 		s.Now = e.Event.When
 	}
+	for _, h := range s.hooks {
+		if h.BeforeEvent != nil {
+			h.BeforeEvent(s, e)
+		}
+	}
 	e.Event.Action(s)
-	return true
+	for _, h := range s.hooks {
+		if h.AfterEvent != nil {
+			h.AfterEvent(s, e)
+		}
+	}
 }
 
 type Action func(*Simulation)
 
 // Schedule schedules an event to be executed at the given time by the simulator. It returns the ID of the event, which can be used to cancel the event before it is executed.
+//
+// Events scheduled before the current time are handled according to the PastPolicy of the simulation. Since Schedule cannot return an error, it panics if the policy is PastReject. Use ScheduleE to get an error instead.
 func (s *Simulation) Schedule(e Event) EventID {
-	id := s.nextID
-	s.queue.Push(scheduledEvent{ID: id, Event: e})
-	s.nextID++
+	id, err := s.ScheduleE(e)
+	if err != nil {
+		panic(err)
+	}
 	return id
 }
 
-// Cancel cancels an event scheduled to the simulation. Returns true if the event was found and cancelled, false if the event was not found (never scheduled, or it was already executed).
-func (s *Simulation) Cancel(id EventID) bool {
-	return s.queue.Remove(id)
+// ScheduleE is like Schedule, but returns an error wrapping ErrScheduledInPast instead of panicking if the event is scheduled before the current time and the PastPolicy of the simulation is PastReject.
+func (s *Simulation) ScheduleE(e Event) (EventID, error) {
+	e, err := s.checkPast(e)
+	if err != nil {
+		return 0, err
+	}
+	return s.schedule(e, false), nil
 }
 
-// RunUntil runs the simulation until the given time or there are no more events to process.
-func (s *Simulation) RunUntil(until time.Time) {
-	for {
-		if s.queue.Len() == 0 {
-			break
+// checkPast applies the PastPolicy of the simulation to an event that is about to be scheduled, returning the event to schedule.
+func (s *Simulation) checkPast(e Event) (Event, error) {
+	if !e.When.Before(s.Now) {
+		return e, nil
+	}
+	switch s.pastPolicy {
+	case PastPanic:
+		panic(s.pastError(e))
+	case PastReject:
+		return e, s.pastError(e)
+	case PastClamp:
+		e.When = s.Now
+	case PastWarn:
+		s.pastEvents++
+		for _, h := range s.hooks {
+			if h.OnPastEvent != nil {
+				h.OnPastEvent(s, e)
+			}
 		}
-		if s.queue.Peek().Event.When.After(until) {
-			// Don't process events after the given time.
-			break
+	}
+	return e, nil
+}
+
+// PastEvents returns the number of events that have been scheduled before the current time using the PastWarn policy.
+func (s *Simulation) PastEvents() int {
+	return s.pastEvents
+}
+
+func (s *Simulation) pastError(e Event) error {
+	return fmt.Errorf("%w: %s is before %s", ErrScheduledInPast, e.When, s.Now)
+}
+
+// At schedules f to be executed at time t. It is shorthand for Schedule(Event{When: t, Action: f}).
+func (s *Simulation) At(t time.Time, f Action) EventID {
+	return s.Schedule(Event{When: t, Action: f})
+}
+
+// After schedules f to be executed after the duration d has passed in the simulation. A zero duration schedules f at the current time, but after all other events that have already been scheduled for the current time (see Immediately for how to jump that queue).
+func (s *Simulation) After(d time.Duration, f Action) EventID {
+	return s.Schedule(Event{When: s.Now.Add(d), Action: f})
+}
+
+// Immediately schedules f to be executed at the current time, as soon as possible. It is run before all events scheduled for the current time using Schedule, At or After with the default Priority of zero, regardless of when those were scheduled. Events with a negative Priority are still run first, see Event.Priority. Events scheduled using Immediately are run in the order they were scheduled.
+//
+// This is useful to wake up actions waiting for a resource, while making sure code is only running within the simulation loop.
+func (s *Simulation) Immediately(f Action) EventID {
+	return s.schedule(Event{When: s.Now, Action: f}, true)
+}
+
+// schedule schedules an event. See ScheduledEvent.Before for the meaning of immediate.
+func (s *Simulation) schedule(e Event, immediate bool) EventID {
+	id := s.ids.get()
+	scheduled := ScheduledEvent{ID: id, Event: e, seq: s.nextSeq, immediate: immediate}
+	s.queue.Push(scheduled)
+	s.nextSeq++
+	for _, h := range s.hooks {
+		if h.OnSchedule != nil {
+			h.OnSchedule(s, scheduled)
 		}
-		if !s.Step() {
-			// Strictly speaking, this should never happen since we have the check for the queue length above. Better safe than sorry, though.
-			break
+	}
+	return id
+}
+
+// Cancel cancels an event scheduled to the simulation. Returns true if the event was found and cancelled, false if the event was not found (never scheduled, or it was already executed).
+func (s *Simulation) Cancel(id EventID) bool {
+	if !s.queue.Remove(id) {
+		return false
+	}
+	s.ids.put(id)
+	for _, h := range s.hooks {
+		if h.OnCancel != nil {
+			h.OnCancel(s, id)
 		}
 	}
+	return true
+}
+
+// Reschedule moves a scheduled event to a new time, keeping its ID. The event is ordered as if it was scheduled now, that is, after other events already scheduled for the same time and priority. Returns true if the event was found and moved, false if the event was not found (never scheduled, cancelled, or already executed).
+//
+// A new time before the current time is handled according to the PastPolicy of the simulation, like for Schedule. Since Reschedule cannot return an error, it panics if the policy is PastReject. Use RescheduleE to get an error instead.
+func (s *Simulation) Reschedule(id EventID, when time.Time) bool {
+	found, err := s.RescheduleE(id, when)
+	if err != nil {
+		panic(err)
+	}
+	return found
 }
 
-// RunUntilDone runs the simulation until there are no more events to process.
-func (s *Simulation) RunUntilDone() {
-	for s.Step() {
-		// Deliberately left empty.
+// RescheduleE is like Reschedule, but returns an error wrapping ErrScheduledInPast instead of panicking if the new time is before the current time and the PastPolicy of the simulation is PastReject. The event is left as it was, and false is returned, if an error is returned.
+func (s *Simulation) RescheduleE(id EventID, when time.Time) (bool, error) {
+	scheduled, found := s.queue.Lookup(id)
+	if !found {
+		return false, nil
+	}
+	scheduled.Event.When = when
+	e, err := s.checkPast(scheduled.Event)
+	if err != nil {
+		return false, err
 	}
+	scheduled.Event = e
+	scheduled.seq = s.nextSeq
+	scheduled.immediate = false
+	s.nextSeq++
+	return s.queue.Update(scheduled), nil
+}
+
+// Lookup returns a scheduled event that has not yet been processed. This can be used to find out when it is due. Returns false if the event was not found (never scheduled, cancelled, or already executed).
+func (s *Simulation) Lookup(id EventID) (Event, bool) {
+	scheduled, found := s.queue.Lookup(id)
+	return scheduled.Event, found
 }
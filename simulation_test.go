@@ -1,6 +1,7 @@
 package steps
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -97,3 +98,352 @@ func TestCancellingNonExistingEvent(t *testing.T) {
 		t.Errorf("expected event to be cancelled")
 	}
 }
+
+func TestCancellingExecutedEvent(t *testing.T) {
+	sim := NewSimulation()
+
+	id := sim.Schedule(Event{When: sim.Now.Add(time.Second), Action: func(s *Simulation) {}})
+	sim.RunUntilDone()
+
+	// The slot of the ID is reused for the next event, but with a new generation.
+	newID := sim.Schedule(Event{When: sim.Now.Add(time.Second), Action: func(s *Simulation) {}})
+	if newID == id {
+		t.Fatalf("expected a new ID, got %d twice", id)
+	}
+	if sim.Cancel(id) {
+		t.Error("expected executed event to not be cancelled")
+	}
+	if !sim.Cancel(newID) {
+		t.Error("expected new event to be cancelled")
+	}
+}
+
+func TestStepDoesNotAllocate(t *testing.T) {
+	sim := NewSimulation()
+	for i := range 100 {
+		Ticker(sim, sim.Now, time.Duration(i+1)*time.Second, func(*Simulation) {})
+	}
+
+	// Warm up, to let the queue grow to its steady state size.
+	for range 1000 {
+		sim.Step()
+	}
+
+	if allocs := testing.AllocsPerRun(1000, func() { sim.Step() }); allocs != 0 {
+		t.Errorf("expected no allocations per step, got %f", allocs)
+	}
+}
+
+func TestScheduleAndCancelDoesNotAllocate(t *testing.T) {
+	sim := NewSimulation()
+	action := func(*Simulation) {}
+
+	// Warm up, to let the queue grow to its steady state size.
+	for range 100 {
+		sim.Schedule(Event{When: sim.Now.Add(time.Second), Action: action})
+	}
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		id := sim.Schedule(Event{When: sim.Now.Add(time.Second), Action: action})
+		sim.Cancel(id)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations per schedule and cancel, got %f", allocs)
+	}
+}
+
+// ExampleSimulation_After shows how to schedule events relative to the current simulation time.
+func ExampleSimulation_After() {
+	sim := NewSimulation()
+
+	sim.After(10*time.Second, func(s *Simulation) {
+		fmt.Println("Actor 1:", s.Now)
+	})
+	sim.After(time.Second, func(s *Simulation) {
+		fmt.Println("Actor 2:", s.Now)
+
+		s.After(time.Second, func(s *Simulation) {
+			fmt.Println("Actor 3:", s.Now)
+		})
+	})
+
+	sim.RunUntilDone()
+
+	// Output:
+	// Actor 2: 0001-01-01 00:00:01 +0000 UTC
+	// Actor 3: 0001-01-01 00:00:02 +0000 UTC
+	// Actor 1: 0001-01-01 00:00:10 +0000 UTC
+}
+
+func TestSchedulingAtTheSameInstant(t *testing.T) {
+	for _, impl := range queueImplementations {
+		t.Run(impl.name, func(t *testing.T) {
+			sim := NewSimulation(WithQueue(impl.newQueue()))
+
+			var order []string
+			record := func(name string) Action {
+				return func(*Simulation) { order = append(order, name) }
+			}
+			sim.After(time.Second, func(s *Simulation) {
+				s.After(0, record("after"))
+				s.At(s.Now, record("at"))
+				s.Immediately(record("immediately 1"))
+				s.Immediately(record("immediately 2"))
+			})
+			sim.After(time.Second, record("scheduled earlier"))
+			sim.RunUntilDone()
+
+			expected := []string{"immediately 1", "immediately 2", "scheduled earlier", "after", "at"}
+			if fmt.Sprint(order) != fmt.Sprint(expected) {
+				t.Errorf("expected order %v, got %v", expected, order)
+			}
+		})
+	}
+}
+
+func TestImmediatelyDoesNotMoveTheClock(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	sim := NewSimulation(WithStartTime(start))
+
+	var ranAt time.Time
+	sim.Immediately(func(s *Simulation) {
+		ranAt = s.Now
+	})
+	sim.RunUntilDone()
+
+	if !ranAt.Equal(start) {
+		t.Errorf("expected to run at %s, got %s", start, ranAt)
+	}
+}
+
+func TestPastPolicies(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	past := start.Add(-time.Second)
+
+	t.Run("Allow", func(t *testing.T) {
+		sim := NewSimulation(WithStartTime(start))
+		var ranAt time.Time
+		sim.At(past, func(s *Simulation) { ranAt = s.Now })
+		sim.RunUntilDone()
+
+		if !ranAt.Equal(start) {
+			t.Errorf("expected event to run at %s, got %s", start, ranAt)
+		}
+	})
+
+	t.Run("Panic", func(t *testing.T) {
+		sim := NewSimulation(WithStartTime(start), WithPastPolicy(PastPanic))
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("expected a panic")
+			}
+		}()
+		sim.ScheduleE(Event{When: past, Action: func(*Simulation) {}})
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		sim := NewSimulation(WithStartTime(start), WithPastPolicy(PastReject))
+		ran := false
+		id, err := sim.ScheduleE(Event{When: past, Action: func(*Simulation) { ran = true }})
+		if !errors.Is(err, ErrScheduledInPast) {
+			t.Errorf("expected ErrScheduledInPast, got %v", err)
+		}
+		if id != 0 {
+			t.Errorf("expected zero ID, got %d", id)
+		}
+		sim.RunUntilDone()
+		if ran {
+			t.Error("expected rejected event to not run")
+		}
+
+		// Now and the future are fine.
+		if _, err := sim.ScheduleE(Event{When: start, Action: func(*Simulation) {}}); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("expected Schedule to panic")
+			}
+		}()
+		sim.After(-time.Second, func(*Simulation) {})
+	})
+
+	t.Run("Clamp", func(t *testing.T) {
+		sim := NewSimulation(WithStartTime(start), WithPastPolicy(PastClamp))
+		var order []string
+		sim.At(start, func(*Simulation) { order = append(order, "now") })
+		sim.At(past, func(*Simulation) { order = append(order, "past") })
+		sim.RunUntilDone()
+
+		// The past event is scheduled at the current time, after the already scheduled one.
+		if expected := []string{"now", "past"}; fmt.Sprint(order) != fmt.Sprint(expected) {
+			t.Errorf("expected order %v, got %v", expected, order)
+		}
+	})
+
+	t.Run("Warn", func(t *testing.T) {
+		var warnings []time.Time
+		sim := NewSimulation(WithStartTime(start), WithPastPolicy(PastWarn), WithHooks(Hooks{
+			OnPastEvent: func(s *Simulation, e Event) { warnings = append(warnings, e.When) },
+		}))
+		ran := false
+		sim.At(past, func(*Simulation) { ran = true })
+		sim.After(time.Second, func(*Simulation) {})
+		sim.RunUntilDone()
+
+		if !ran {
+			t.Error("expected event to run")
+		}
+		if sim.PastEvents() != 1 {
+			t.Errorf("expected 1 past event, got %d", sim.PastEvents())
+		}
+		if len(warnings) != 1 || !warnings[0].Equal(past) {
+			t.Errorf("expected a single warning for %s, got %v", past, warnings)
+		}
+	})
+
+	t.Run("Immediately is never in the past", func(t *testing.T) {
+		sim := NewSimulation(WithStartTime(start), WithPastPolicy(PastPanic))
+		c := NewCondition(sim)
+		ran := false
+		c.Wait(func(*Simulation) { ran = true })
+		sim.After(time.Second, func(*Simulation) { c.Signal() })
+		sim.RunUntilDone()
+
+		if !ran {
+			t.Error("expected woken action to run")
+		}
+	})
+}
+
+func TestEventPriority(t *testing.T) {
+	for _, impl := range queueImplementations {
+		t.Run(impl.name, func(t *testing.T) {
+			sim := NewSimulation(WithQueue(impl.newQueue()))
+
+			var order []string
+			record := func(name string) Action {
+				return func(*Simulation) { order = append(order, name) }
+			}
+			when := sim.Now.Add(time.Second)
+			sim.Schedule(Event{When: when, Action: record("arrival 1"), Priority: 1})
+			sim.Schedule(Event{When: when, Action: record("default")})
+			sim.Schedule(Event{When: when, Action: record("arrival 2"), Priority: 1})
+			sim.Schedule(Event{When: when, Action: record("departure"), Priority: -1})
+			sim.Schedule(Event{When: when.Add(-time.Nanosecond), Action: record("earlier"), Priority: 10})
+			sim.RunUntilDone()
+
+			expected := []string{"earlier", "departure", "default", "arrival 1", "arrival 2"}
+			if fmt.Sprint(order) != fmt.Sprint(expected) {
+				t.Errorf("expected order %v, got %v", expected, order)
+			}
+		})
+	}
+}
+
+func TestEventPriorityBeforeImmediately(t *testing.T) {
+	for _, impl := range queueImplementations {
+		t.Run(impl.name, func(t *testing.T) {
+			sim := NewSimulation(WithQueue(impl.newQueue()))
+			c := NewCondition(sim)
+
+			var order []string
+			record := func(name string) Action {
+				return func(*Simulation) { order = append(order, name) }
+			}
+			c.WaitWithPriority(10, record("woken up"))
+			sim.After(time.Second, func(sim *Simulation) {
+				sim.Schedule(Event{When: sim.Now, Action: record("default")})
+				sim.Schedule(Event{When: sim.Now, Action: record("urgent"), Priority: -10})
+				sim.Immediately(record("immediately"))
+				c.Signal()
+			})
+			sim.RunUntilDone()
+
+			expected := []string{"urgent", "immediately", "default", "woken up"}
+			if fmt.Sprint(order) != fmt.Sprint(expected) {
+				t.Errorf("expected order %v, got %v", expected, order)
+			}
+		})
+	}
+}
+
+func TestReschedule(t *testing.T) {
+	for _, impl := range queueImplementations {
+		t.Run(impl.name, func(t *testing.T) {
+			sim := NewSimulation(WithQueue(impl.newQueue()))
+
+			var order []string
+			record := func(name string) Action {
+				return func(*Simulation) { order = append(order, name) }
+			}
+			timeout := sim.After(time.Second, record("timeout"))
+			sim.After(2*time.Second, record("other"))
+			sim.After(3*time.Second, record("last"))
+
+			// Extend the timeout, past the other event.
+			if !sim.Reschedule(timeout, sim.Now.Add(2*time.Second)) {
+				t.Fatal("expected event to be rescheduled")
+			}
+			e, found := sim.Lookup(timeout)
+			if !found {
+				t.Fatal("expected event to be found")
+			}
+			if expected := sim.Now.Add(2 * time.Second); !e.When.Equal(expected) {
+				t.Errorf("expected event to be due at %s, got %s", expected, e.When)
+			}
+
+			sim.RunUntilDone()
+
+			// The rescheduled event is ordered after events already scheduled for the same time.
+			if expected := []string{"other", "timeout", "last"}; fmt.Sprint(order) != fmt.Sprint(expected) {
+				t.Errorf("expected order %v, got %v", expected, order)
+			}
+			if sim.Reschedule(timeout, sim.Now.Add(time.Second)) {
+				t.Error("expected executed event to not be rescheduled")
+			}
+			if _, found := sim.Lookup(timeout); found {
+				t.Error("expected executed event to not be found")
+			}
+		})
+	}
+}
+
+func TestRescheduleCancelledEvent(t *testing.T) {
+	sim := NewSimulation()
+	id := sim.After(time.Second, func(*Simulation) {})
+	sim.Cancel(id)
+
+	if sim.Reschedule(id, sim.Now.Add(time.Second)) {
+		t.Error("expected cancelled event to not be rescheduled")
+	}
+}
+
+func TestRescheduleRejectedInPast(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sim := NewSimulation(WithStartTime(start), WithPastPolicy(PastReject))
+	id := sim.After(time.Second, func(*Simulation) {})
+
+	moved, err := sim.RescheduleE(id, start.Add(-time.Second))
+	if !errors.Is(err, ErrScheduledInPast) {
+		t.Errorf("expected ErrScheduledInPast, got %v", err)
+	}
+	if moved {
+		t.Error("expected the event to not be rescheduled")
+	}
+	if e, _ := sim.Lookup(id); !e.When.Equal(start.Add(time.Second)) {
+		t.Errorf("expected the event to still be due at %s, got %s", start.Add(time.Second), e.When)
+	}
+
+	if moved, err := sim.RescheduleE(id, start); !moved || err != nil {
+		t.Errorf("expected the event to be rescheduled now, got %v and %v", moved, err)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected Reschedule to panic")
+		}
+	}()
+	sim.Reschedule(id, start.Add(-time.Second))
+}
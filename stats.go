@@ -0,0 +1,214 @@
+package steps
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+)
+
+// ResourceStats are statistics about how a resource has been used, recorded against simulation time. See WithStats.
+//
+// What is "in use" depends on the resource: units held for a semaphore, items held for a store and the level of a container. The queue is made up of everyone waiting for the resource, for example both puts and gets waiting for a store.
+type ResourceStats struct {
+	// Elapsed is the simulation time covered by the statistics, from when the resource was created until now.
+	Elapsed time.Duration
+
+	// MeanInUse is the time-weighted mean of what was in use, and MaxInUse is the maximum. Utilization is MeanInUse relative to the capacity of the resource, or zero if the resource is unbounded.
+	MeanInUse   float64
+	MaxInUse    float64
+	Utilization float64
+
+	// MeanQueueLength is the time-weighted mean number of requests waiting for the resource, and MaxQueueLength is the maximum.
+	MeanQueueLength float64
+	MaxQueueLength  int
+
+	// Served is the number of requests that have been served, whether they had to wait or not. Throughput is the number of requests served per second of simulation time.
+	Served     int
+	Throughput float64
+
+	// Waits is the distribution of how long requests waited before being served.
+	Waits WaitStats
+}
+
+// WaitStats is a distribution of waiting times. Count, Mean, Min and Max are exact, while quantiles are estimated using a histogram, so that the memory used doesn't grow with the number of waits recorded.
+type WaitStats struct {
+	Count int
+	Mean  time.Duration
+	Min   time.Duration
+	Max   time.Duration
+
+	// buckets holds the non-empty buckets of the histogram, in increasing order.
+	buckets []waitBucket
+}
+
+// Quantile returns an estimate of the q-quantile of the waiting times, where q is between zero and one. For example, Quantile(0.95) is the waiting time that 95% of the requests didn't exceed. The estimate is within 1% of the actual waiting time, and exact if the waiting times are few or far apart. Returns zero if there are no waiting times.
+func (w WaitStats) Quantile(q float64) time.Duration {
+	if w.Count == 0 {
+		return 0
+	}
+	q = min(max(q, 0), 1)
+	rank := int(q*float64(w.Count-1) + 0.5)
+	for _, b := range w.buckets {
+		if rank < b.count {
+			return b.estimate()
+		}
+		rank -= b.count
+	}
+	return w.Max
+}
+
+// waitAccuracy is the relative accuracy of the waiting time histogram. Waiting times are put in buckets whose bounds grow by a factor of waitGamma, so that any waiting time in a bucket is within waitAccuracy of the middle of the bucket.
+const waitAccuracy = 0.01
+
+var waitGamma = (1 + waitAccuracy) / (1 - waitAccuracy)
+
+// waitBucket counts the waiting times between waitGamma^(index-1) and waitGamma^index nanoseconds. Zero waiting times have their own bucket, with index math.MinInt.
+type waitBucket struct {
+	index int
+	count int
+
+	// min and max are the shortest and longest waiting times in the bucket.
+	min time.Duration
+	max time.Duration
+}
+
+// waitBucketIndex returns the index of the bucket that d belongs to.
+func waitBucketIndex(d time.Duration) int {
+	if d <= 0 {
+		return math.MinInt
+	}
+	return int(math.Ceil(math.Log(float64(d)) / math.Log(waitGamma)))
+}
+
+// estimate returns an estimate of any waiting time in the bucket, which is exact if all of them are the same.
+func (b waitBucket) estimate() time.Duration {
+	if b.min == b.max || b.index == math.MinInt {
+		return b.min
+	}
+	middle := time.Duration(2 * math.Pow(waitGamma, float64(b.index)) / (waitGamma + 1))
+	return min(max(middle, b.min), b.max)
+}
+
+// statsRecorder records ResourceStats for a resource. All methods can be called on a nil recorder, which records nothing, so that resources don't have to check whether statistics are enabled.
+type statsRecorder struct {
+	sim      *Simulation
+	capacity float64
+
+	// observe returns what is currently in use, and how many requests are waiting.
+	observe func() (inUse float64, queue int)
+
+	start time.Time
+	last  time.Time
+
+	// inUse and queue are the values observed at last. The areas are their integrals over time, in seconds.
+	inUse     float64
+	queue     int
+	inUseArea float64
+	queueArea float64
+	maxInUse  float64
+	maxQueue  int
+
+	// waits holds the histogram of waiting times, by bucket index. waitSum is the sum of all waiting times, in nanoseconds, as a float64 to not overflow.
+	waits   map[int]*waitBucket
+	waitSum float64
+	minWait time.Duration
+	maxWait time.Duration
+	served  int
+}
+
+// newStatsRecorder returns a recorder if statistics are enabled in o, and nil otherwise. A capacity of zero or less means that the resource is unbounded.
+func newStatsRecorder(sim *Simulation, o resourceOptions, capacity float64, observe func() (float64, int)) *statsRecorder {
+	if !o.stats {
+		return nil
+	}
+	r := &statsRecorder{
+		sim:      sim,
+		capacity: capacity,
+		observe:  observe,
+		start:    sim.Now,
+		last:     sim.Now,
+		waits:    make(map[int]*waitBucket),
+	}
+	r.update()
+	return r
+}
+
+// update records the current state of the resource. It must be called whenever what is in use or the queue changes.
+func (r *statsRecorder) update() {
+	if r == nil {
+		return
+	}
+	if r.sim.Now.After(r.last) {
+		elapsed := r.sim.Now.Sub(r.last).Seconds()
+		r.inUseArea += r.inUse * elapsed
+		r.queueArea += float64(r.queue) * elapsed
+		r.last = r.sim.Now
+	}
+	r.inUse, r.queue = r.observe()
+	r.maxInUse = max(r.maxInUse, r.inUse)
+	r.maxQueue = max(r.maxQueue, r.queue)
+}
+
+// serve records that a request has been served, after waiting since it was made.
+func (r *statsRecorder) serve(since time.Time) {
+	if r == nil {
+		return
+	}
+	wait := r.sim.Now.Sub(since)
+	if r.served == 0 {
+		r.minWait, r.maxWait = wait, wait
+	}
+	r.served++
+	r.waitSum += float64(wait)
+	r.minWait = min(r.minWait, wait)
+	r.maxWait = max(r.maxWait, wait)
+
+	index := waitBucketIndex(wait)
+	b, ok := r.waits[index]
+	if !ok {
+		b = &waitBucket{index: index, min: wait, max: wait}
+		r.waits[index] = b
+	}
+	b.count++
+	b.min = min(b.min, wait)
+	b.max = max(b.max, wait)
+}
+
+// stats returns the statistics recorded so far, or the zero ResourceStats if r is nil.
+func (r *statsRecorder) stats() ResourceStats {
+	if r == nil {
+		return ResourceStats{}
+	}
+	r.update()
+	s := ResourceStats{
+		Elapsed:        r.sim.Now.Sub(r.start),
+		MaxInUse:       r.maxInUse,
+		MaxQueueLength: r.maxQueue,
+		Served:         r.served,
+	}
+	if seconds := s.Elapsed.Seconds(); seconds > 0 {
+		s.MeanInUse = r.inUseArea / seconds
+		s.MeanQueueLength = r.queueArea / seconds
+		s.Throughput = float64(r.served) / seconds
+	}
+	if r.capacity > 0 {
+		s.Utilization = s.MeanInUse / r.capacity
+	}
+
+	if r.served > 0 {
+		buckets := make([]waitBucket, 0, len(r.waits))
+		for _, b := range r.waits {
+			buckets = append(buckets, *b)
+		}
+		slices.SortFunc(buckets, func(a, b waitBucket) int { return cmp.Compare(a.index, b.index) })
+		s.Waits = WaitStats{
+			Count:   r.served,
+			Mean:    time.Duration(r.waitSum / float64(r.served)),
+			Min:     r.minWait,
+			Max:     r.maxWait,
+			buckets: buckets,
+		}
+	}
+	return s
+}
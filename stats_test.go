@@ -0,0 +1,142 @@
+package steps
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// ExampleWithStats simulates customers arriving every minute to a single server that takes 90 seconds to serve each customer, and prints statistics about the server after an hour.
+func ExampleWithStats() {
+	sim := NewSimulation()
+	server := NewBinarySemaphore(sim, WithStats())
+
+	Ticker(sim, sim.Now, time.Minute, func(sim *Simulation) {
+		server.Acquire(func(sim *Simulation) {
+			sim.After(90*time.Second, func(*Simulation) { server.Release() })
+		})
+	})
+	sim.RunUntil(sim.Now.Add(time.Hour))
+
+	stats := server.Stats()
+	fmt.Printf("Utilization: %.2f\n", stats.Utilization)
+	fmt.Printf("Mean queue length: %.2f (max %d)\n", stats.MeanQueueLength, stats.MaxQueueLength)
+	fmt.Printf("Served: %d, mean wait %s, max wait %s\n", stats.Served, stats.Waits.Mean, stats.Waits.Max)
+
+	// Output:
+	// Utilization: 1.00
+	// Mean queue length: 10.00 (max 20)
+	// Served: 41, mean wait 10m0s, max wait 20m0s
+}
+
+func TestCountingSemaphoreStats(t *testing.T) {
+	sim := NewSimulation()
+	sem := NewCountingSemaphore(sim, 1, WithStats())
+
+	for range 3 {
+		sem.Acquire(func(sim *Simulation) {
+			sim.After(10*time.Second, func(*Simulation) { sem.Release() })
+		})
+	}
+	sim.RunUntilDone()
+
+	stats := sem.Stats()
+	if stats.Elapsed != 30*time.Second {
+		t.Errorf("expected 30s elapsed, got %s", stats.Elapsed)
+	}
+	if stats.Utilization != 1 || stats.MeanInUse != 1 || stats.MaxInUse != 1 {
+		t.Errorf("expected full utilization, got %+v", stats)
+	}
+	// Two are waiting for 10 seconds, then one for 10 seconds, out of 30 seconds.
+	if stats.MeanQueueLength != 1 || stats.MaxQueueLength != 2 {
+		t.Errorf("expected a mean queue length of 1 and a max of 2, got %g and %d", stats.MeanQueueLength, stats.MaxQueueLength)
+	}
+	if stats.Served != 3 || stats.Throughput != 0.1 {
+		t.Errorf("expected 3 served at 0.1 per second, got %d at %g", stats.Served, stats.Throughput)
+	}
+	waits := stats.Waits
+	if waits.Count != 3 || waits.Min != 0 || waits.Mean != 10*time.Second || waits.Max != 20*time.Second {
+		t.Errorf("expected waits of 0s, 10s and 20s, got %+v", waits)
+	}
+	if q := waits.Quantile(0.5); q != 10*time.Second {
+		t.Errorf("expected a median wait of 10s, got %s", q)
+	}
+	if q := waits.Quantile(1); q != 20*time.Second {
+		t.Errorf("expected the max wait to be the 1-quantile, got %s", q)
+	}
+}
+
+func TestStatsAreDisabledByDefault(t *testing.T) {
+	sim := NewSimulation()
+	sem := NewCountingSemaphore(sim, 1)
+	sem.Acquire(func(*Simulation) {})
+	sim.RunUntilDone()
+
+	if stats := sem.Stats(); stats.Served != 0 || stats.Waits.Quantile(0.5) != 0 {
+		t.Errorf("expected no statistics, got %+v", stats)
+	}
+}
+
+func TestStoreStats(t *testing.T) {
+	sim := NewSimulation()
+	store := NewStore[int](sim, 4, WithStats())
+
+	store.Get(func(*Simulation, int) {})
+	sim.After(10*time.Second, func(*Simulation) {
+		for i := range 3 {
+			store.Put(i, nil)
+		}
+	})
+	sim.AdvanceTo(sim.Now.Add(20 * time.Second))
+
+	stats := store.Stats()
+	if stats.Served != 1 || stats.Waits.Max != 10*time.Second {
+		t.Errorf("expected one get served after 10s, got %d and %s", stats.Served, stats.Waits.Max)
+	}
+	// Two items are held for 10 out of 20 seconds, in a store with room for four.
+	if stats.MeanInUse != 1 || stats.MaxInUse != 2 || stats.Utilization != 0.25 {
+		t.Errorf("expected a mean of 1 item, a max of 2 and 25%% utilization, got %+v", stats)
+	}
+	if stats.MeanQueueLength != 0.5 {
+		t.Errorf("expected a mean queue length of 0.5, got %g", stats.MeanQueueLength)
+	}
+}
+
+func TestContainerStats(t *testing.T) {
+	sim := NewSimulation()
+	c := NewContainer(sim, 10, 10, WithStats())
+
+	sim.After(10*time.Second, func(*Simulation) { c.Get(5, func(*Simulation) {}) })
+	sim.AdvanceTo(sim.Now.Add(20 * time.Second))
+
+	stats := c.Stats()
+	if stats.MeanInUse != 7.5 || stats.Utilization != 0.75 || stats.Served != 1 {
+		t.Errorf("expected a mean level of 7.5, 75%% utilization and one get, got %+v", stats)
+	}
+}
+
+func TestWaitQuantilesUseBoundedMemory(t *testing.T) {
+	sim := NewSimulation()
+	r := newStatsRecorder(sim, resourceOptions{stats: true}, 1, func() (float64, int) { return 0, 0 })
+
+	const n = 100000
+	for i := 1; i <= n; i++ {
+		r.serve(sim.Now.Add(-time.Duration(i) * time.Millisecond))
+	}
+	if len(r.waits) > 1000 {
+		t.Errorf("expected a bounded number of buckets, got %d", len(r.waits))
+	}
+
+	waits := r.stats().Waits
+	if waits.Count != n || waits.Min != time.Millisecond || waits.Max != n*time.Millisecond {
+		t.Errorf("expected %d waits from 1ms to %dms, got %+v", n, n, waits)
+	}
+	for _, q := range []float64{0.01, 0.5, 0.95, 0.99} {
+		expected := time.Duration(q*(n-1)+1.5) * time.Millisecond
+		got := waits.Quantile(q)
+		if diff := math.Abs(float64(got-expected)) / float64(expected); diff > 0.01 {
+			t.Errorf("expected the %g-quantile to be within 1%% of %s, got %s", q, expected, got)
+		}
+	}
+}
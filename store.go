@@ -0,0 +1,407 @@
+package steps
+
+import (
+	"container/heap"
+	"slices"
+	"time"
+)
+
+// Store passes items between actions, for example from producers to consumers. Items are got in the order they were put. A store has an optional capacity. Putting an item in a full store waits until there is room, and getting an item from an empty store waits until an item is put. Waiting puts and gets are served in the order they started waiting.
+//
+// Puts and gets return a Request, which can be used to stop waiting. A put which didn't have to wait is served right away, while a get which didn't have to wait can be cancelled until its action runs, as long as there is room in the store to put its item back.
+type Store[T any] struct {
+	store[T]
+}
+
+// NewStore creates a new, empty, store that can hold capacity items. A capacity of zero or less means the store is unbounded, so putting an item never waits.
+func NewStore[T any](sim *Simulation, capacity int, opts ...ResourceOption) *Store[T] {
+	s := &Store[T]{}
+	s.init(sim, capacity, &fifoItems[T]{}, newResourceOptions("Store", opts))
+	return s
+}
+
+// Get gets the next item from the store and passes it to a. If the store is empty, a will be scheduled to run once an item is put.
+func (s *Store[T]) Get(a func(*Simulation, T)) *Request {
+	return s.get(nil, a)
+}
+
+// TryGet gets the next item from the store only if one is available right now, without waiting. Note that items aren't available to TryGet while there are actions waiting to get them.
+func (s *Store[T]) TryGet() (T, bool) {
+	return s.tryGet(nil)
+}
+
+// FilterStore is like a Store, but every get only accepts items matching a filter. A get waits until an item matching its filter is put, without blocking other gets.
+type FilterStore[T any] struct {
+	store[T]
+}
+
+// NewFilterStore creates a new, empty, filter store that can hold capacity items. A capacity of zero or less means the store is unbounded.
+func NewFilterStore[T any](sim *Simulation, capacity int, opts ...ResourceOption) *FilterStore[T] {
+	s := &FilterStore[T]{}
+	s.filtered = true
+	s.init(sim, capacity, &fifoItems[T]{}, newResourceOptions("FilterStore", opts))
+	return s
+}
+
+// Get gets the first item in the store for which filter returns true and passes it to a. If there is no such item, a will be scheduled to run once one is put.
+func (s *FilterStore[T]) Get(filter func(T) bool, a func(*Simulation, T)) *Request {
+	return s.get(filter, a)
+}
+
+// TryGet gets the first item in the store for which filter returns true, only if there is one right now, without waiting.
+func (s *FilterStore[T]) TryGet(filter func(T) bool) (T, bool) {
+	return s.tryGet(filter)
+}
+
+// PriorityStore is like a Store, but items are got in priority order instead of in the order they were put. Items with the same priority are got in the order they were put.
+type PriorityStore[T any] struct {
+	store[T]
+}
+
+// NewPriorityStore creates a new, empty, priority store that can hold capacity items. A capacity of zero or less means the store is unbounded. less reports whether an item should be got before another.
+func NewPriorityStore[T any](sim *Simulation, capacity int, less func(a, b T) bool, opts ...ResourceOption) *PriorityStore[T] {
+	s := &PriorityStore[T]{}
+	s.init(sim, capacity, &priorityItems[T]{less: less}, newResourceOptions("PriorityStore", opts))
+	return s
+}
+
+// Get gets the item with the highest priority from the store and passes it to a. If the store is empty, a will be scheduled to run once an item is put.
+func (s *PriorityStore[T]) Get(a func(*Simulation, T)) *Request {
+	return s.get(nil, a)
+}
+
+// TryGet gets the item with the highest priority from the store only if one is available right now, without waiting.
+func (s *PriorityStore[T]) TryGet() (T, bool) {
+	return s.tryGet(nil)
+}
+
+// store is the implementation shared by all stores.
+type store[T any] struct {
+	sim      *Simulation
+	capacity int
+	items    storeItems[T]
+
+	// filtered is set if gets can have filters, in which case a waiting get doesn't block the ones behind it.
+	filtered bool
+
+	// getters and putters hold the actions waiting to get and put items. gets and puts hold the details of each of them.
+	getters *Condition
+	gets    map[ConditionActionID]*storeGet[T]
+	putters *Condition
+	puts    map[ConditionActionID]*storePut[T]
+
+	// stats records statistics, if enabled using WithStats.
+	stats *statsRecorder
+}
+
+// storeGet is a get waiting for an item since requested.
+type storeGet[T any] struct {
+	filter    func(T) bool
+	item      T
+	requested time.Time
+}
+
+// storePut is a put waiting for room.
+type storePut[T any] struct {
+	item T
+}
+
+func (s *store[T]) init(sim *Simulation, capacity int, items storeItems[T], o resourceOptions) {
+	s.sim = sim
+	s.capacity = capacity
+	s.items = items
+	s.getters = NewCondition(sim)
+	s.gets = make(map[ConditionActionID]*storeGet[T])
+	s.putters = NewCondition(sim)
+	s.puts = make(map[ConditionActionID]*storePut[T])
+	s.stats = newStatsRecorder(sim, o, float64(capacity), func() (float64, int) {
+		return float64(s.items.len()), s.getters.Waiting() + s.putters.Waiting()
+	})
+}
+
+// Stats returns statistics about how the store has been used, where the items held count as being in use and every get served counts as a request served. Returns the zero ResourceStats unless the store was created using WithStats.
+func (s *store[T]) Stats() ResourceStats {
+	return s.stats.stats()
+}
+
+// Len returns the number of items in the store.
+func (s *store[T]) Len() int {
+	return s.items.len()
+}
+
+// Cap returns the capacity of the store, or zero if it is unbounded.
+func (s *store[T]) Cap() int {
+	return max(s.capacity, 0)
+}
+
+// Put puts an item in the store and schedules a to run as soon as possible. If the store is full, the item is put, and a is scheduled, once there is room. a may be nil.
+func (s *store[T]) Put(item T, a Action) *Request {
+	if s.canPut() {
+		s.items.push(item)
+		if a != nil {
+			s.sim.Immediately(a)
+		}
+		// Wake up the gets after scheduling a, so that the put is seen to happen first.
+		s.dispatch()
+		return &Request{}
+	}
+
+	put := &storePut[T]{item: item}
+	id := s.putters.Wait(func(sim *Simulation) {
+		if a != nil {
+			a(sim)
+		}
+	})
+	s.puts[id] = put
+	s.stats.update()
+	return newRequest(func() bool {
+		if !s.putters.Cancel(id) {
+			return false
+		}
+		delete(s.puts, id)
+		s.stats.update()
+		return true
+	})
+}
+
+// TryPut puts an item in the store only if there is room right now, without waiting. Returns true if the item was put. Note that there is no room for TryPut while there are actions waiting to put items.
+func (s *store[T]) TryPut(item T) bool {
+	if !s.canPut() {
+		return false
+	}
+	s.items.push(item)
+	s.dispatch()
+	return true
+}
+
+// canPut returns whether an item can be put right now, without jumping the queue.
+func (s *store[T]) canPut() bool {
+	return s.hasRoom() && s.putters.Waiting() == 0
+}
+
+func (s *store[T]) get(filter func(T) bool, a func(*Simulation, T)) *Request {
+	if item, ok := s.take(filter); ok {
+		var event EventID
+		event = s.sim.Immediately(func(sim *Simulation) {
+			event = 0
+			a(sim, item)
+		})
+		// Wake up the puts after scheduling a, so that the get is seen to happen first.
+		s.dispatch()
+		return newRequest(func() bool {
+			// The room freed by taking the item might already have been given to a put, in which case the item cannot be put back.
+			if event == 0 || !s.hasRoom() || !s.sim.Cancel(event) {
+				return false
+			}
+			// The item was taken, but never used. Put it back first in line.
+			event = 0
+			s.items.pushFront(item)
+			s.dispatch()
+			return true
+		})
+	}
+
+	get := &storeGet[T]{filter: filter, requested: s.sim.Now}
+	id := s.getters.Wait(func(sim *Simulation) {
+		a(sim, get.item)
+	})
+	s.gets[id] = get
+	s.stats.update()
+	return newRequest(func() bool {
+		if !s.getters.Cancel(id) {
+			return false
+		}
+		delete(s.gets, id)
+		s.stats.update()
+		return true
+	})
+}
+
+func (s *store[T]) tryGet(filter func(T) bool) (T, bool) {
+	item, ok := s.take(filter)
+	if ok {
+		s.dispatch()
+	}
+	return item, ok
+}
+
+// take takes an item right now, without jumping the queue, but doesn't wake up anyone waiting.
+func (s *store[T]) take(filter func(T) bool) (T, bool) {
+	if !s.filtered && s.getters.Waiting() > 0 {
+		var zero T
+		return zero, false
+	}
+	item, ok := s.items.take(filter)
+	if ok {
+		s.stats.serve(s.sim.Now)
+	}
+	return item, ok
+}
+
+func (s *store[T]) hasRoom() bool {
+	return s.capacity <= 0 || s.items.len() < s.capacity
+}
+
+// dispatch hands items over to waiting gets, and room over to waiting puts, until neither can make progress.
+func (s *store[T]) dispatch() {
+	for progressed := true; progressed; {
+		progressed = false
+		if s.filtered {
+			if s.getters.Waiting() > 0 && s.items.len() > 0 {
+				for id := range s.getters.ordered() {
+					progressed = s.serveGet(id) || progressed
+				}
+			}
+		} else {
+			for {
+				id, ok := s.getters.front()
+				if !ok || !s.serveGet(id) {
+					break
+				}
+				progressed = true
+			}
+		}
+
+		for s.hasRoom() {
+			id, ok := s.putters.front()
+			if !ok {
+				break
+			}
+			put := s.puts[id]
+			delete(s.puts, id)
+			s.items.push(put.item)
+			s.putters.signalID(id)
+			progressed = true
+		}
+	}
+	s.stats.update()
+}
+
+// serveGet hands an item over to the waiting get with the given ID, if there is one it accepts. Returns false otherwise.
+func (s *store[T]) serveGet(id ConditionActionID) bool {
+	get, ok := s.gets[id]
+	if !ok {
+		// The get was cancelled while serving another one, for example by a Select.
+		return false
+	}
+	item, ok := s.items.take(get.filter)
+	if !ok {
+		return false
+	}
+	get.item = item
+	delete(s.gets, id)
+	s.stats.serve(get.requested)
+	s.getters.signalID(id)
+	return true
+}
+
+// storeItems holds the items of a store.
+type storeItems[T any] interface {
+	// push adds an item.
+	push(item T)
+
+	// pushFront adds an item that was taken back, so that it is the next one to be taken.
+	pushFront(item T)
+
+	// take removes and returns the next item for which filter returns true. A nil filter accepts any item.
+	take(filter func(T) bool) (T, bool)
+
+	len() int
+}
+
+// fifoItems holds items in the order they were put.
+type fifoItems[T any] struct {
+	items []T
+}
+
+func (q *fifoItems[T]) push(item T) {
+	q.items = append(q.items, item)
+}
+
+func (q *fifoItems[T]) pushFront(item T) {
+	q.items = slices.Insert(q.items, 0, item)
+}
+
+func (q *fifoItems[T]) take(filter func(T) bool) (T, bool) {
+	for i, item := range q.items {
+		if filter == nil || filter(item) {
+			q.items = slices.Delete(q.items, i, i+1)
+			return item, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+func (q *fifoItems[T]) len() int {
+	return len(q.items)
+}
+
+// priorityItems holds items in a heap, ordered by less and then the order they were put.
+type priorityItems[T any] struct {
+	less  func(a, b T) bool
+	items []priorityItem[T]
+
+	// next is the order of the next item pushed. front is the order of the next item pushed to the front, which decreases.
+	next  int
+	front int
+}
+
+type priorityItem[T any] struct {
+	item  T
+	order int
+}
+
+func (q *priorityItems[T]) push(item T) {
+	heap.Push(q, priorityItem[T]{item: item, order: q.next})
+	q.next++
+}
+
+func (q *priorityItems[T]) pushFront(item T) {
+	q.front--
+	heap.Push(q, priorityItem[T]{item: item, order: q.front})
+}
+
+// take ignores filter, since a PriorityStore doesn't support filters.
+func (q *priorityItems[T]) take(filter func(T) bool) (T, bool) {
+	if len(q.items) == 0 {
+		var zero T
+		return zero, false
+	}
+	return heap.Pop(q).(priorityItem[T]).item, true
+}
+
+func (q *priorityItems[T]) len() int {
+	return len(q.items)
+}
+
+// Len, Less, Swap, Push and Pop implement heap.Interface.
+func (q *priorityItems[T]) Len() int {
+	return len(q.items)
+}
+
+func (q *priorityItems[T]) Less(i, j int) bool {
+	a, b := q.items[i], q.items[j]
+	if q.less(a.item, b.item) {
+		return true
+	}
+	if q.less(b.item, a.item) {
+		return false
+	}
+	return a.order < b.order
+}
+
+func (q *priorityItems[T]) Swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+}
+
+func (q *priorityItems[T]) Push(x any) {
+	q.items = append(q.items, x.(priorityItem[T]))
+}
+
+func (q *priorityItems[T]) Pop() any {
+	n := len(q.items)
+	x := q.items[n-1]
+	q.items = q.items[:n-1]
+	return x
+}
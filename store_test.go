@@ -0,0 +1,205 @@
+package steps
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// ExampleStore simulates a fast producer and a slow consumer, passing items through a store with room for two (2) items.
+func ExampleStore() {
+	sim := NewSimulation()
+	store := NewStore[int](sim, 2)
+
+	var produce func(i int)
+	produce = func(i int) {
+		if i == 5 {
+			return
+		}
+		store.Put(i, func(sim *Simulation) {
+			fmt.Println(sim.Now, "Produced", i)
+			sim.After(time.Second, func(*Simulation) { produce(i + 1) })
+		})
+	}
+	produce(0)
+
+	var consume func(*Simulation)
+	consume = func(*Simulation) {
+		store.Get(func(sim *Simulation, i int) {
+			fmt.Println(sim.Now, "Consumed", i)
+			sim.After(3*time.Second, consume)
+		})
+	}
+	sim.After(5*time.Second, consume)
+	sim.RunUntil(sim.Now.Add(time.Minute))
+
+	// Output:
+	// 0001-01-01 00:00:00 +0000 UTC Produced 0
+	// 0001-01-01 00:00:01 +0000 UTC Produced 1
+	// 0001-01-01 00:00:05 +0000 UTC Consumed 0
+	// 0001-01-01 00:00:05 +0000 UTC Produced 2
+	// 0001-01-01 00:00:08 +0000 UTC Consumed 1
+	// 0001-01-01 00:00:08 +0000 UTC Produced 3
+	// 0001-01-01 00:00:11 +0000 UTC Consumed 2
+	// 0001-01-01 00:00:11 +0000 UTC Produced 4
+	// 0001-01-01 00:00:14 +0000 UTC Consumed 3
+	// 0001-01-01 00:00:17 +0000 UTC Consumed 4
+}
+
+func TestStoreGetsAreServedInOrder(t *testing.T) {
+	sim := NewSimulation()
+	store := NewStore[string](sim, 0)
+
+	var got []string
+	for i := range 3 {
+		store.Get(func(_ *Simulation, item string) {
+			got = append(got, fmt.Sprint(i, ":", item))
+		})
+	}
+	for _, item := range []string{"a", "b", "c", "d"} {
+		store.Put(item, nil)
+	}
+	sim.RunUntilDone()
+
+	if expected := []string{"0:a", "1:b", "2:c"}; fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if store.Len() != 1 || store.Cap() != 0 {
+		t.Errorf("expected one item in an unbounded store, got %d items and capacity %d", store.Len(), store.Cap())
+	}
+	if item, ok := store.TryGet(); !ok || item != "d" {
+		t.Errorf("expected to get d, got %q", item)
+	}
+	if _, ok := store.TryGet(); ok {
+		t.Error("expected the store to be empty")
+	}
+}
+
+func TestStoreCancel(t *testing.T) {
+	sim := NewSimulation()
+	store := NewStore[int](sim, 1)
+
+	var got []int
+	record := func(_ *Simulation, i int) { got = append(got, i) }
+
+	waiting := store.Get(func(*Simulation, int) { t.Error("expected the cancelled get to never run") })
+	if !waiting.Cancel() || waiting.Cancel() {
+		t.Error("expected the waiting get to be cancelled once")
+	}
+
+	store.Put(1, nil)
+	blocked := store.Put(2, func(*Simulation) { t.Error("expected the cancelled put to never run") })
+	if !blocked.Cancel() {
+		t.Error("expected the blocked put to be cancelled")
+	}
+
+	// A get served right away puts the item back if it is cancelled before it runs.
+	taken := store.Get(func(*Simulation, int) { t.Error("expected the cancelled get to never run") })
+	if store.Len() != 0 {
+		t.Error("expected the item to be taken")
+	}
+	if !taken.Cancel() {
+		t.Error("expected the served get to be cancelled before it ran")
+	}
+	store.Get(record)
+	sim.RunUntilDone()
+
+	if expected := []int{1}; fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if store.Len() != 0 {
+		t.Errorf("expected the store to be empty, got %d items", store.Len())
+	}
+}
+
+func TestStoreCancelAfterRoomWasTaken(t *testing.T) {
+	sim := NewSimulation()
+	store := NewStore[int](sim, 1)
+
+	var got []int
+	record := func(_ *Simulation, i int) { got = append(got, i) }
+
+	store.Put(1, nil)
+	store.Put(2, nil)
+	taken := store.Get(record)
+	if store.Len() != 1 {
+		t.Errorf("expected the blocked put to take the freed room, got %d items", store.Len())
+	}
+	if taken.Cancel() {
+		t.Error("expected to not cancel the get, since there is no room to put the item back")
+	}
+	store.Get(record)
+	sim.RunUntilDone()
+
+	if expected := []int{1, 2}; fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if store.Len() > store.Cap() {
+		t.Errorf("expected at most %d items, got %d", store.Cap(), store.Len())
+	}
+}
+
+func TestStoreTryPut(t *testing.T) {
+	sim := NewSimulation()
+	store := NewStore[int](sim, 1)
+
+	if !store.TryPut(1) {
+		t.Error("expected the item to be put")
+	}
+	if store.TryPut(2) {
+		t.Error("expected the full store to not accept an item")
+	}
+}
+
+func TestFilterStore(t *testing.T) {
+	sim := NewSimulation()
+	store := NewFilterStore[int](sim, 0)
+
+	var got []string
+	record := func(name string) func(*Simulation, int) {
+		return func(_ *Simulation, i int) { got = append(got, fmt.Sprint(name, ":", i)) }
+	}
+	isEven := func(i int) bool { return i%2 == 0 }
+	isOdd := func(i int) bool { return i%2 == 1 }
+
+	// A get waiting for an even item doesn't block the one waiting for an odd item.
+	store.Get(isEven, record("even"))
+	store.Get(isOdd, record("odd"))
+	store.Put(1, nil)
+	store.Put(3, nil)
+	sim.RunUntilDone()
+	if expected := []string{"odd:1"}; fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	store.Put(4, nil)
+	sim.RunUntilDone()
+	if expected := []string{"odd:1", "even:4"}; fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if item, ok := store.TryGet(isOdd); !ok || item != 3 {
+		t.Errorf("expected to get 3, got %d", item)
+	}
+}
+
+func TestPriorityStore(t *testing.T) {
+	sim := NewSimulation()
+	type job struct {
+		name     string
+		priority int
+	}
+	store := NewPriorityStore(sim, 0, func(a, b job) bool { return a.priority < b.priority })
+
+	for _, j := range []job{{"low", 2}, {"high", 0}, {"medium 1", 1}, {"medium 2", 1}} {
+		store.Put(j, nil)
+	}
+	var got []string
+	for range 4 {
+		store.Get(func(_ *Simulation, j job) { got = append(got, j.name) })
+	}
+	sim.RunUntilDone()
+
+	if expected := []string{"high", "medium 1", "medium 2", "low"}; fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}